@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dekonix/manga-converter/internal"
+)
+
+// runCommand handles one-shot subcommands; without arguments the converter
+// runs as a watcher.
+func runCommand(name string, args []string) int {
+	switch name {
+	case "hash":
+		return hashCommand(args)
+	case "status":
+		return statusCommand()
+	case "pin":
+		return pinCommand(args)
+	case "retag":
+		return retagCommand(args)
+	default:
+		fmt.Fprintf(os.Stderr, "неизвестная команда %q\n", name)
+		fmt.Fprintln(os.Stderr, "использование: converter [hash <изображение>... | status | pin <название> <источник>=<ID>... | retag [-dry-run] [папка]]")
+		return 2
+	}
+}
+
+// hashCommand prints perceptual hashes in blocklist format so junk pages can
+// be added to the dedupe blocklist.
+func hashCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "использование: converter hash <изображение>...")
+		return 2
+	}
+	status := 0
+	for _, path := range args {
+		hash, err := internal.HashFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("%s # %s\n", hash, path)
+	}
+	return status
+}
+
+// statusCommand lists the chapters waiting in the accumulation staging area
+// and what each volume is still missing.
+func statusCommand() int {
+	cfg, err := internal.LoadSettings("config.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка конфигурации: %v\n", err)
+		return 1
+	}
+	internal.Config = cfg
+
+	statuses, err := internal.StagingStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "накопитель: %v\n", err)
+		return 1
+	}
+	if len(statuses) == 0 {
+		fmt.Println("Накопитель пуст")
+		return 0
+	}
+	for _, s := range statuses {
+		fmt.Println(s.Series)
+		if s.Error != "" {
+			fmt.Printf("  ошибка: %s\n", s.Error)
+		}
+		for _, v := range s.Volumes {
+			if len(v.Missing) == 0 {
+				fmt.Printf("  Том %s: все главы на месте (%s)\n", v.Volume, strings.Join(v.Staged, ", "))
+				continue
+			}
+			fmt.Printf("  Том %s: есть %s, не хватает %s\n", v.Volume, strings.Join(v.Staged, ", "), strings.Join(v.Missing, ", "))
+		}
+		if len(s.Unassigned) > 0 {
+			fmt.Printf("  Без тома: %s\n", strings.Join(s.Unassigned, ", "))
+		}
+	}
+	return 0
+}
+
+// pinCommand pins a series to provider IDs so later volumes are never
+// searched by name: "pin One_Piece shikimori=13" or, for a regular
+// expression, "pin -pattern '^One.?Piece' mangadex=<uuid>".
+func pinCommand(args []string) int {
+	usage := "использование: converter pin [-pattern] <название> shikimori=<ID> anilist=<ID> mangadex=<UUID>"
+	var pin internal.SeriesPin
+	if len(args) > 0 && args[0] == "-pattern" {
+		args = args[1:]
+		if len(args) > 0 {
+			pin.Pattern = args[0]
+		}
+	} else if len(args) > 0 {
+		pin.Name = args[0]
+	}
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	for _, arg := range args[1:] {
+		provider, id, _ := strings.Cut(arg, "=")
+		var err error
+		switch provider {
+		case "shikimori":
+			pin.Shikimori, err = strconv.Atoi(id)
+		case "anilist":
+			pin.AniList, err = strconv.Atoi(id)
+		case "mangadex":
+			pin.MangaDex = id
+		default:
+			err = fmt.Errorf("неизвестный источник %q", provider)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n%s\n", arg, err, usage)
+			return 2
+		}
+	}
+
+	cfg, err := internal.LoadSettings("config.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка конфигурации: %v\n", err)
+		return 1
+	}
+	if err := internal.SavePin(cfg.Pins, pin); err != nil {
+		fmt.Fprintf(os.Stderr, "привязка: %v\n", err)
+		return 1
+	}
+	fmt.Printf("📌 Привязка сохранена в %s\n", cfg.Pins)
+	return 0
+}
+
+// retagCommand refreshes ComicInfo.xml of an existing library, by default
+// output/cbz. With -dry-run it only prints what would change.
+func retagCommand(args []string) int {
+	dryRun := false
+	if len(args) > 0 && args[0] == "-dry-run" {
+		dryRun, args = true, args[1:]
+	}
+	if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "использование: converter retag [-dry-run] [папка]")
+		return 2
+	}
+	root := "output/cbz"
+	if len(args) == 1 {
+		root = args[0]
+	}
+
+	cfg, err := internal.LoadSettings("config.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка конфигурации: %v\n", err)
+		return 1
+	}
+	internal.Config = cfg
+
+	results, err := internal.RetagLibrary(root, dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "retag: %v\n", err)
+		return 1
+	}
+	status, changed := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			fmt.Printf("%s: ошибка: %s\n", r.Path, r.Error)
+			status = 1
+		case len(r.Diff) > 0:
+			changed++
+			fmt.Println(r.Path)
+			for _, line := range r.Diff {
+				fmt.Println("  " + line)
+			}
+		}
+	}
+	verb := "обновлено"
+	if dryRun {
+		verb = "будет обновлено"
+	}
+	fmt.Printf("Архивов: %d, %s: %d\n", len(results), verb, changed)
+	return status
+}
@@ -16,9 +16,19 @@ const stableWindow = 2 * time.Second
 const checkInterval = 300 * time.Millisecond
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	log.SetOutput(os.Stdout)
 	log.Println("🚀 Manga converter (fsnotify) started")
 
+	cfg, err := internal.LoadSettings("config.json")
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+	internal.Config = cfg
+
 	inputDir := "input"
 
 	// One-time scan on startup (in case files already exist)
@@ -5,6 +5,7 @@ go 1.24.5
 require (
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/google/uuid v1.6.0
+	golang.org/x/text v0.32.0
 )
 
 require golang.org/x/sys v0.13.0 // indirect
@@ -1,20 +1,44 @@
 package internal
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
 type AniResponse struct {
 	Data struct {
 		Media struct {
+			ID    int `json:"id"`
+			IDMal int `json:"idMal"`
 			Title struct {
 				Romaji  string `json:"romaji"`
 				English string `json:"english"`
+				Native  string `json:"native"`
 			} `json:"title"`
 			Description string   `json:"description"`
 			Genres      []string `json:"genres"`
-			CoverImage  struct {
+			Tags        []struct {
+				Name string `json:"name"`
+			} `json:"tags"`
+			Status     string `json:"status"`
+			Volumes    int    `json:"volumes"`
+			Chapters   int    `json:"chapters"`
+			IsAdult    bool   `json:"isAdult"`
+			CoverImage struct {
 				ExtraLarge string `json:"extraLarge"`
+				Large      string `json:"large"`
 			} `json:"coverImage"`
-			SiteURL string `json:"siteUrl"`
-			Staff   struct {
+			SiteURL   string `json:"siteUrl"`
+			StartDate struct {
+				Year int `json:"year"`
+			} `json:"startDate"`
+			Staff struct {
 				Edges []struct {
+					Role string `json:"role"`
 					Node struct {
 						Name struct {
 							Full string `json:"full"`
@@ -26,6 +50,92 @@ type AniResponse struct {
 	} `json:"data"`
 }
 
+// FetchAniListByID fetches a series pinned to an AniList ID; name is the
+// folder name used as the fallback title.
+func FetchAniListByID(id int, name string) (*Metadata, error) {
+	query := `query ($id: Int) {
+      Media(id: $id, type: MANGA) {
+        id idMal
+        title { romaji english native }
+        description(asHtml: false)
+        genres
+        tags { name }
+        status volumes chapters isAdult
+        coverImage { extraLarge large }
+        siteUrl
+        startDate { year }
+        staff {
+          edges { role node { name { full } } }
+        }
+      }
+    }`
+
+	jsonBody, err := json.Marshal(map[string]any{
+		"query":     query,
+		"variables": map[string]int{"id": id},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🔍 Запрос к AniList по ID: %d", id)
+	resp, err := http.Post("https://graphql.anilist.co", "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("статус ответа %d", resp.StatusCode)
+	}
+
+	var result AniResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("декодирование ответа: %w", err)
+	}
+
+	m := result.Data.Media
+	series := Series{
+		Titles: Titles{
+			English: m.Title.English,
+			Romaji:  m.Title.Romaji,
+			Native:  m.Title.Native,
+		},
+		Description:  m.Description,
+		Genres:       m.Genres,
+		Year:         m.StartDate.Year,
+		Status:       aniListStatuses[m.Status],
+		AgeRating:    ratingByGenres(m.Genres),
+		VolumeCount:  m.Volumes,
+		ChapterCount: m.Chapters,
+		URL:          m.SiteURL,
+		IDs:          ProviderIDs{AniList: m.ID, MyAnimeList: m.IDMal},
+	}
+	if m.IsAdult {
+		series.AgeRating = AgeRatingAdult
+	}
+	for _, tag := range m.Tags {
+		series.Tags = append(series.Tags, tag.Name)
+	}
+	for _, edge := range m.Staff.Edges {
+		series.addCreator(edge.Node.Name.Full, edge.Role)
+	}
+	for _, image := range []string{m.CoverImage.ExtraLarge, m.CoverImage.Large} {
+		if image != "" {
+			series.Covers = append(series.Covers, Cover{URL: image})
+		}
+	}
+	return newMetadata(series, strings.ReplaceAll(name, "_", " ")), nil
+}
+
+var aniListStatuses = map[string]string{
+	"NOT_YET_RELEASED": StatusAnnounced,
+	"RELEASING":        StatusOngoing,
+	"FINISHED":         StatusCompleted,
+	"HIATUS":           StatusHiatus,
+	"CANCELLED":        StatusCancelled,
+}
+
 // type Metadata struct {
 // 	Title       string
 // 	Author      string
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+const (
+	BlankKeep   = "keep"   // only report blank pages
+	BlankRemove = "remove" // drop every blank page
+	BlankParity = "parity" // drop blank pages but keep the two-page layout
+)
+
+// blankBand is the luminance distance from the dominant tone within which a
+// pixel counts towards the uniform share.
+const blankBand = 16
+
+type BlankSettings struct {
+	Enabled bool   `json:"enabled"`
+	Policy  string `json:"policy"`
+	// A page is blank when the standard deviation of its luminance is at most
+	// MaxStdDev, or when at least MinUniformShare of its pixels are close to
+	// the dominant tone (a stray page number or dust on a white page).
+	MaxStdDev       float64 `json:"max_stddev"`
+	MinUniformShare float64 `json:"min_uniform_share"`
+}
+
+// RemoveBlankPages finds white or black separator pages and applies the
+// policy. With BlankParity, only pairs of consecutive blank pages are removed
+// so every other page keeps its left/right position in a two-page view;
+// trailing blank pages are always dropped.
+func RemoveBlankPages(pages []*Page, cfg BlankSettings, report *VolumeReport) ([]*Page, error) {
+	blank := make([]bool, len(pages))
+	err := forEachPage(pages, func(i int, p *Page) error {
+		if p.Problem != "" {
+			return nil
+		}
+		img, _, err := decodeImage(p.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		blank[i] = isBlank(img, cfg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	remove := make([]bool, len(pages))
+	switch cfg.Policy {
+	case BlankRemove:
+		copy(remove, blank)
+	case BlankParity:
+		for i := 0; i < len(pages); {
+			if !blank[i] {
+				i++
+				continue
+			}
+			j := i
+			for j < len(pages) && blank[j] {
+				j++
+			}
+			n := j - i
+			if j < len(pages) && n%2 == 1 {
+				// An odd run keeps one page to hold the parity of what follows.
+				n--
+			}
+			for k := i; k < i+n; k++ {
+				remove[k] = true
+			}
+			i = j
+		}
+	}
+
+	out := make([]*Page, 0, len(pages))
+	for i, p := range pages {
+		if !blank[i] {
+			out = append(out, p)
+			continue
+		}
+		if remove[i] {
+			removePage(p, report, "blank", "пустая страница")
+			continue
+		}
+		report.AddFinding(Finding{Page: p.Name, Problem: "blank", Detail: "пустая страница", Action: "kept"})
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func isBlank(img image.Image, cfg BlankSettings) bool {
+	b := img.Bounds()
+	total := b.Dx() * b.Dy()
+	if total == 0 {
+		return false
+	}
+
+	var hist [256]int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			hist[luma(img, x, y)]++
+		}
+	}
+
+	var sum, sumSq float64
+	mode := 0
+	for v, n := range hist {
+		sum += float64(v * n)
+		sumSq += float64(v * v * n)
+		if n > hist[mode] {
+			mode = v
+		}
+	}
+	mean := sum / float64(total)
+	stddev := math.Sqrt(max(sumSq/float64(total)-mean*mean, 0))
+	if stddev <= cfg.MaxStdDev {
+		return true
+	}
+
+	near := 0
+	for v := max(mode-blankBand, 0); v <= min(mode+blankBand, 255); v++ {
+		near += hist[v]
+	}
+	return float64(near)/float64(total) >= cfg.MinUniformShare
+}
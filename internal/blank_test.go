@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"image"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsBlank(t *testing.T) {
+	cfg := DefaultSettings().Blank
+
+	white := framedPage(100, 100, image.Rectangle{})
+	if !isBlank(white, cfg) {
+		t.Fatal("white page should be blank")
+	}
+	pageNumber := framedPage(100, 100, image.Rect(48, 95, 52, 97))
+	if !isBlank(pageNumber, cfg) {
+		t.Fatal("white page with a page number should be blank")
+	}
+	content := framedPage(100, 100, image.Rect(10, 10, 90, 90))
+	if isBlank(content, cfg) {
+		t.Fatal("page with content should not be blank")
+	}
+}
+
+func blankVolume(t *testing.T, layout string) []*Page {
+	t.Helper()
+	dir := t.TempDir()
+	for i, kind := range layout {
+		content := image.Rect(10, 10, 50, 50)
+		if kind == 'b' {
+			content = image.Rectangle{}
+		}
+		name := string(rune('a'+i)) + ".png"
+		writeImageFile(t, filepath.Join(dir, name), framedPage(60, 60, content))
+	}
+	pages, err := LoadPages(dir)
+	if err != nil {
+		t.Fatalf("LoadPages error: %v", err)
+	}
+	return pages
+}
+
+func pageNames(pages []*Page) string {
+	var names []string
+	for _, p := range pages {
+		names = append(names, strings.TrimSuffix(p.Name, ".png"))
+	}
+	return strings.Join(names, "")
+}
+
+func TestRemoveBlankPagesPolicies(t *testing.T) {
+	// p = page with content, b = blank page; letters name the files a, b, c...
+	const layout = "pbpbbpbbbpb"
+	cases := []struct {
+		policy string
+		want   string
+	}{
+		{BlankKeep, "abcdefghijk"},
+		{BlankRemove, "acfj"},
+		// Single blank b is kept, the pair d-e is removed, one of g-h-i is
+		// kept, trailing k is dropped.
+		{BlankParity, "abcfij"},
+	}
+
+	for _, tc := range cases {
+		cfg := DefaultSettings().Blank
+		cfg.Policy = tc.policy
+		got, err := RemoveBlankPages(blankVolume(t, layout), cfg, &VolumeReport{})
+		if err != nil {
+			t.Fatalf("%s: RemoveBlankPages error: %v", tc.policy, err)
+		}
+		if names := pageNames(got); names != tc.want {
+			t.Fatalf("%s: kept %s, want %s", tc.policy, names, tc.want)
+		}
+	}
+}
@@ -2,26 +2,116 @@ package internal
 
 import (
 	"archive/zip"
-	"fmt"
+	"encoding/xml"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"strings"
 )
 
-func CreateCBZ(folder string, meta *Metadata, output string) error {
-	xml := fmt.Sprintf(`<ComicInfo>
-  <Title>%s</Title>
-  <Writer>%s</Writer>
-  <Summary>%s</Summary>
-  <Genre>%s</Genre>
-  <Web>%s</Web>
-</ComicInfo>`, meta.Title, meta.Author, meta.Description, meta.Genres, meta.URL)
-
-	xmlPath := filepath.Join(folder, "ComicInfo.xml")
-	err := os.WriteFile(xmlPath, []byte(xml), 0644)
+// comicInfo follows the ComicInfo 2.0 element order.
+type comicInfo struct {
+	XMLName         xml.Name `xml:"ComicInfo"`
+	Title           string   `xml:"Title"`
+	Series          string   `xml:"Series,omitempty"`
+	LocalizedSeries string   `xml:"LocalizedSeries,omitempty"`
+	Number          string   `xml:"Number,omitempty"`
+	Count           int      `xml:"Count,omitempty"`
+	Volume          string   `xml:"Volume,omitempty"`
+	Summary         string   `xml:"Summary"`
+	Notes           string   `xml:"Notes,omitempty"`
+	Year            int      `xml:"Year,omitempty"`
+	Writer          string   `xml:"Writer"`
+	Penciller       string   `xml:"Penciller,omitempty"`
+	Translator      string   `xml:"Translator,omitempty"`
+	Publisher       string   `xml:"Publisher,omitempty"`
+	Genre           string   `xml:"Genre"`
+	Tags            string   `xml:"Tags,omitempty"`
+	Web             string   `xml:"Web"`
+	LanguageISO     string   `xml:"LanguageISO,omitempty"`
+	ScanInformation string   `xml:"ScanInformation,omitempty"`
+	AgeRating       string   `xml:"AgeRating,omitempty"`
+
+	// Extra keeps elements written by other tools or by hand (StoryArc,
+	// Characters, CommunityRating...) when an existing file is rewritten.
+	Extra []comicInfoElement `xml:",any"`
+	Pages []comicInfoPage    `xml:"Pages>Page,omitempty"`
+}
+
+type comicInfoElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+type comicInfoPage struct {
+	Image       int    `xml:"Image,attr"`
+	Type        string `xml:"Type,attr,omitempty"`
+	DoublePage  bool   `xml:"DoublePage,attr,omitempty"`
+	Bookmark    string `xml:"Bookmark,attr,omitempty"`
+	ImageWidth  int    `xml:"ImageWidth,attr,omitempty"`
+	ImageHeight int    `xml:"ImageHeight,attr,omitempty"`
+
+	// Extra keeps unknown attributes such as Key or ImageSize.
+	Extra []xml.Attr `xml:",any,attr"`
+}
+
+// releaseTagsNote prefixes the release tags in ComicInfo Notes.
+const releaseTagsNote = "Теги релиза: "
+
+func buildComicInfo(pages []*Page, meta *Metadata) ([]byte, error) {
+	info := newComicInfo(meta)
+	for i, p := range pages {
+		pageType := ""
+		if p.FrontCover {
+			pageType = "FrontCover"
+		}
+		info.Pages = append(info.Pages, comicInfoPage{
+			Image:       i,
+			Type:        pageType,
+			DoublePage:  p.DoublePage,
+			Bookmark:    p.Bookmark,
+			ImageWidth:  p.Width,
+			ImageHeight: p.Height,
+		})
+	}
+	return xml.MarshalIndent(info, "", "  ")
+}
+
+// newComicInfo fills everything but the page list.
+func newComicInfo(meta *Metadata) comicInfo {
+	series := meta.Series
+	info := comicInfo{
+		Title:           meta.Title,
+		Series:          series.Title,
+		LocalizedSeries: firstOf(series.AltTitles), // one name, the preferred alternate
+		Number:          meta.Number,
+		Count:           series.VolumeCount,
+		Volume:          meta.Volume,
+		Summary:         series.Description,
+		Year:            series.Year,
+		Writer:          strings.Join(series.People(RoleWriter), ", "),
+		Penciller:       strings.Join(series.People(RoleArtist), ", "),
+		Publisher:       strings.Join(series.Publishers, ", "),
+		Genre:           strings.Join(series.Genres, ", "),
+		Tags:            strings.Join(series.Tags, ", "),
+		Web:             series.URL,
+		LanguageISO:     meta.Language,
+		AgeRating:       series.AgeRating,
+		// The scanlation group is both the translator and the scanner.
+		Translator:      meta.Group,
+		ScanInformation: meta.Group,
+	}
+	if len(meta.ReleaseTags) > 0 {
+		info.Notes = releaseTagsNote + strings.Join(meta.ReleaseTags, ", ")
+	}
+	return info
+}
+
+func CreateCBZ(pages []*Page, meta *Metadata, output string) error {
+	xmlData, err := buildComicInfo(pages, meta)
 	if err != nil {
-		log.Printf("❌ Ошибка записи ComicInfo.xml: %v", err)
+		log.Printf("❌ Ошибка формирования ComicInfo.xml: %v", err)
 		return err
 	}
 
@@ -37,38 +127,43 @@ func CreateCBZ(folder string, meta *Metadata, output string) error {
 
 	log.Printf("📦 Упаковка CBZ: %s", output)
 
-	err = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			log.Printf("⚠️ Ошибка обхода файла: %v", err)
-			return err
-		}
-		if info.IsDir() {
-			return nil
+	err = addPages(zipWriter, pages)
+	if err == nil {
+		var writer io.Writer
+		writer, err = zipWriter.Create("ComicInfo.xml")
+		if err == nil {
+			_, err = writer.Write(xmlData)
 		}
+	}
 
-		relPath, _ := filepath.Rel(folder, path)
-		writer, err := zipWriter.Create(relPath)
+	if err != nil {
+		log.Printf("❌ Ошибка упаковки CBZ: %v", err)
+	} else {
+		log.Printf("✅ CBZ создан: %s", output)
+	}
+
+	return err
+}
+
+func addPages(zipWriter *zip.Writer, pages []*Page) error {
+	for _, p := range pages {
+		writer, err := zipWriter.Create(p.Name)
 		if err != nil {
 			log.Printf("❌ Не удалось создать файл в архиве: %v", err)
 			return err
 		}
 
-		file, err := os.Open(path)
+		file, err := os.Open(p.Path)
 		if err != nil {
 			log.Printf("❌ Не удалось открыть файл: %v", err)
 			return err
 		}
-		defer file.Close()
 
 		_, err = io.Copy(writer, file)
-		return err
-	})
-
-	if err != nil {
-		log.Printf("❌ Ошибка упаковки CBZ: %v", err)
-	} else {
-		log.Printf("✅ CBZ создан: %s", output)
+		file.Close()
+		if err != nil {
+			return err
+		}
 	}
-
-	return err
+	return nil
 }
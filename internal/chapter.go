@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var firstNumber = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// chapter is a run of pages of one chapter inside a volume.
+type chapter struct {
+	Number string // parsed chapter number, empty if the folder has none
+	Label  string // number or folder name, used in file names and titles
+	Pages  []*Page
+}
+
+// chapterNumber extracts a chapter number from a folder or file name. File
+// names need a chapter keyword ("ch12_003.jpg"), while for folders any number
+// will do ("012", "Глава 12").
+func chapterNumber(name string, keyword bool) string {
+	info := ParseName(name)
+	if info.Chapter != "" || keyword {
+		return info.Chapter
+	}
+	return trimNumber(firstNumber.FindString(info.Title))
+}
+
+// trimNumber drops leading zeros: "012" becomes "12", "000.5" becomes "0.5".
+func trimNumber(n string) string {
+	if n == "" {
+		return ""
+	}
+	whole, frac, hasFrac := strings.Cut(n, ".")
+	whole = strings.TrimLeft(whole, "0")
+	if whole == "" {
+		whole = "0"
+	}
+	if hasFrac {
+		return whole + "." + frac
+	}
+	return whole
+}
+
+// splitChapters groups the pages of a volume by chapter folder or by the
+// chapter number in the file name. Pages without a chapter (a cover before
+// the first chapter, credits at the end) join the neighbouring chapter.
+// Returns nil when no chapters are found.
+func splitChapters(pages []*Page) []chapter {
+	type pageKey struct {
+		key string
+		dir bool
+	}
+	keys := make([]pageKey, len(pages))
+	first := -1
+	for i, p := range pages {
+		if dir := path.Dir(p.Name); dir != "." {
+			keys[i] = pageKey{dir, true}
+		} else {
+			keys[i] = pageKey{chapterNumber(path.Base(p.Name), true), false}
+		}
+		if keys[i].key != "" && first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+
+	for i := range keys {
+		switch {
+		case i < first:
+			keys[i] = keys[first]
+		case keys[i].key == "":
+			keys[i] = keys[i-1]
+		}
+	}
+
+	var chapters []chapter
+	for i, p := range pages {
+		if i == 0 || keys[i] != keys[i-1] {
+			c := chapter{Number: keys[i].key, Label: keys[i].key}
+			if keys[i].dir {
+				name := path.Base(keys[i].key)
+				c.Number = ParseName(name).ChapterRange()
+				if c.Number == "" {
+					c.Number = chapterNumber(name, false)
+				}
+				c.Label = name
+				if c.Number != "" {
+					c.Label = c.Number
+				}
+			}
+			chapters = append(chapters, c)
+		}
+		chapters[len(chapters)-1].Pages = append(chapters[len(chapters)-1].Pages, p)
+	}
+	return chapters
+}
+
+// convertChapters writes one book per chapter of a processed volume. Returns
+// false when the volume has no detectable chapters.
+func convertChapters(pages []*Page, volumeName string, mangaName string, meta *Metadata, report *VolumeReport) (bool, error) {
+	chapters := splitChapters(pages)
+	if chapters == nil {
+		return false, nil
+	}
+	log.Printf("📑 Том %s: найдено глав — %d", volumeName, len(chapters))
+
+	for _, c := range chapters {
+		chapterMeta := *meta
+		chapterMeta.Title = fmt.Sprintf("%s — %s, глава %s", meta.Series.Title, volumeTitle(volumeName), c.Label)
+		chapterMeta.Volume = parseVolumeName(volumeName).Volume
+		chapterMeta.Number = c.Number
+		name := bookName{
+			Folder: mangaName,
+			Label:  volumeFileLabel(volumeName) + "__Chapter_" + padNumber(c.Label, 3),
+		}
+		if err := writeOutputs(c.Pages, &chapterMeta, name, report); err != nil {
+			return true, fmt.Errorf("глава %s: %w", c.Label, err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChapterNumber(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyword bool
+		want    string
+	}{
+		{"ch012_003.jpg", true, "12"},
+		{"c5.5_p01.png", true, "5.5"},
+		{"Глава 7 - 01.jpg", true, "7"},
+		{"003.jpg", true, ""},
+		{"cover.jpg", true, ""},
+		{"012", false, "12"},
+		{"Vol.2 Ch.3", false, "3"},
+		{"Extra", false, ""},
+	}
+	for _, tc := range cases {
+		if got := chapterNumber(tc.name, tc.keyword); got != tc.want {
+			t.Fatalf("chapterNumber(%q, %v) = %q, want %q", tc.name, tc.keyword, got, tc.want)
+		}
+	}
+}
+
+func chapterSummary(chapters []chapter) [][]string {
+	var out [][]string
+	for _, c := range chapters {
+		row := []string{c.Number, c.Label}
+		for _, p := range c.Pages {
+			row = append(row, p.Name)
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func TestSplitChaptersByFileName(t *testing.T) {
+	var pages []*Page
+	for _, name := range []string{"cover.jpg", "ch01_001.jpg", "ch01_002.jpg", "ch02_001.jpg", "credits.jpg"} {
+		pages = append(pages, &Page{Name: name})
+	}
+	want := [][]string{
+		{"1", "1", "cover.jpg", "ch01_001.jpg", "ch01_002.jpg"},
+		{"2", "2", "ch02_001.jpg", "credits.jpg"},
+	}
+	if got := chapterSummary(splitChapters(pages)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitChapters = %v, want %v", got, want)
+	}
+}
+
+func TestSplitChaptersByFolder(t *testing.T) {
+	var pages []*Page
+	for _, name := range []string{"Глава 1/001.jpg", "Глава 1/002.jpg", "Extra/001.jpg"} {
+		pages = append(pages, &Page{Name: name})
+	}
+	want := [][]string{
+		{"1", "1", "Глава 1/001.jpg", "Глава 1/002.jpg"},
+		{"", "Extra", "Extra/001.jpg"},
+	}
+	if got := chapterSummary(splitChapters(pages)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitChapters = %v, want %v", got, want)
+	}
+
+	if got := splitChapters([]*Page{{Name: "001.jpg"}, {Name: "002.jpg"}}); got != nil {
+		t.Fatalf("expected no chapters, got %v", chapterSummary(got))
+	}
+}
+
+func TestConvertChapters(t *testing.T) {
+	tmp := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	dir := filepath.Join(tmp, "pages")
+	var pages []*Page
+	for _, name := range []string{"ch03_001.jpg", "ch04_001.jpg"} {
+		writeJPEG(t, filepath.Join(dir, name), 10, 10)
+		pages = append(pages, &Page{Path: filepath.Join(dir, name), Name: name, Width: 10, Height: 10})
+	}
+
+	report := NewJobReport("test.zip").Volume("Volume 2")
+	done, err := convertChapters(pages, "Volume 2", "TestManga", &Metadata{Title: "Test Title", Series: Series{Title: "Test Title"}}, report)
+	if err != nil || !done {
+		t.Fatalf("convertChapters = %v, %v", done, err)
+	}
+	if len(report.Outputs) != 2 {
+		t.Fatalf("outputs = %v, want 2 books", report.Outputs)
+	}
+
+	out := filepath.Join("output", "cbz", "Test Title", "TestManga__Vol_02__Chapter_004.cbz")
+	_, contents := readZipEntries(t, out)
+	info := contents["ComicInfo.xml"]
+	for _, want := range []string{"<Number>4</Number>", "<Volume>2</Volume>", "Test Title — Том 2, глава 4"} {
+		if !strings.Contains(info, want) {
+			t.Fatalf("ComicInfo.xml missing %s, got %s", want, info)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Settings holds the tunable parts of the conversion pipeline.
+// Fields missing from config.json keep their DefaultSettings values.
+type Settings struct {
+	// Granularity selects one book per volume, per chapter or an omnibus
+	// of OmnibusVolumes volumes (0 = the whole series).
+	Granularity    string `json:"granularity"`
+	OmnibusVolumes int    `json:"omnibus_volumes"`
+
+	Accumulate AccumulateSettings `json:"accumulate"`
+	Naming     NamingSettings     `json:"naming"`
+	// TitlePreference is the order in which provider titles are tried for
+	// the series name.
+	TitlePreference []string `json:"title_preference"`
+	// Pins is the file pinning series to provider IDs, see SeriesPin.
+	Pins  string        `json:"pins"`
+	Cover CoverSettings `json:"cover"`
+	// SeriesJSON keeps a Mylar series.json in every CBZ series folder.
+	SeriesJSON bool `json:"series_json"`
+
+	JPEGQuality int                `json:"jpeg_quality"`
+	Parallel    ParallelSettings   `json:"parallel"`
+	Validation  ValidationSettings `json:"validation"`
+	Normalize   NormalizeSettings  `json:"normalize"`
+	Dedupe      DedupeSettings     `json:"dedupe"`
+	Blank       BlankSettings      `json:"blank"`
+	Crop        CropSettings       `json:"crop"`
+	Profiles    []OutputProfile    `json:"profiles"`
+}
+
+type CropSettings struct {
+	Enabled bool `json:"enabled"`
+	// Tolerance is the maximum luminance deviation (0-255) from the border
+	// colour that still counts as border.
+	Tolerance uint8 `json:"tolerance"`
+	// MinContentRatio protects nearly empty pages: if the cropped area is
+	// smaller than this share of the original, the page is left untouched.
+	MinContentRatio float64 `json:"min_content_ratio"`
+}
+
+// Config is the active configuration used by ProcessZip.
+var Config = DefaultSettings()
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		Granularity:     GranularityVolume,
+		TitlePreference: []string{TitleRussian, TitleEnglish, TitleRomaji, TitleNative, TitleFolder},
+		Pins:            "pins.json",
+		Cover: CoverSettings{
+			Enabled:         true,
+			SeriesFiles:     true,
+			Prepend:         false,
+			MinColorfulness: 0.05,
+		},
+		SeriesJSON: true,
+		Naming: NamingSettings{
+			Dir:     DefaultDirTemplate,
+			File:    DefaultFileTemplate,
+			Charset: CharsetUnicode,
+		},
+		Accumulate: AccumulateSettings{
+			Enabled:  false,
+			Staging:  "staging",
+			Mapping:  "chapters.json",
+			Provider: ProviderMangaDex,
+		},
+		JPEGQuality: 90,
+		Parallel: ParallelSettings{
+			Workers:  0,
+			MemoryMB: 1024,
+		},
+		Validation: ValidationSettings{
+			Policy: ValidationWarn,
+		},
+		Normalize: NormalizeSettings{
+			Enabled: true,
+		},
+		Dedupe: DedupeSettings{
+			Enabled:     false,
+			Blocklist:   "blocklist.txt",
+			MaxDistance: 6,
+		},
+		Blank: BlankSettings{
+			Enabled:         false,
+			Policy:          BlankParity,
+			MaxStdDev:       6,
+			MinUniformShare: 0.995,
+		},
+		Crop: CropSettings{
+			Enabled:         false,
+			Tolerance:       16,
+			MinContentRatio: 0.5,
+		},
+		Profiles: []OutputProfile{DefaultProfile()},
+	}
+}
+
+// LoadSettings reads a JSON config on top of the defaults. A missing file is
+// not an error: the defaults are returned.
+func LoadSettings(path string) (*Settings, error) {
+	cfg := DefaultSettings()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	for i := range cfg.Profiles {
+		cfg.Profiles[i].applyDefaults()
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("проверка %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (s *Settings) Validate() error {
+	switch s.Granularity {
+	case GranularityVolume, GranularityChapter, GranularityOmnibus:
+	default:
+		return fmt.Errorf("granularity: неизвестный режим %q", s.Granularity)
+	}
+	if s.OmnibusVolumes < 0 {
+		return errors.New("omnibus_volumes не может быть отрицательным")
+	}
+	switch s.Accumulate.Provider {
+	case ProviderMangaDex, ProviderNone:
+	default:
+		return fmt.Errorf("accumulate.provider: неизвестный источник %q", s.Accumulate.Provider)
+	}
+	if s.Accumulate.Enabled && s.Accumulate.Staging == "" {
+		return errors.New("accumulate.staging не задан")
+	}
+	if err := validateTitlePreference(s.TitlePreference); err != nil {
+		return err
+	}
+	if err := s.Naming.validate(false); err != nil {
+		return fmt.Errorf("naming: %w", err)
+	}
+	if s.Cover.MinColorfulness < 0 || s.Cover.MinColorfulness > 1 {
+		return fmt.Errorf("cover.min_colorfulness должно быть в диапазоне 0..1, получено %g", s.Cover.MinColorfulness)
+	}
+	if s.JPEGQuality < 1 || s.JPEGQuality > 100 {
+		return fmt.Errorf("jpeg_quality должно быть в диапазоне 1..100, получено %d", s.JPEGQuality)
+	}
+	if s.Parallel.Workers < 0 || s.Parallel.MemoryMB < 0 {
+		return errors.New("parallel: workers и memory_mb не могут быть отрицательными")
+	}
+	switch s.Validation.Policy {
+	case ValidationFail, ValidationSkip, ValidationWarn:
+	default:
+		return fmt.Errorf("validation.policy: неизвестная политика %q", s.Validation.Policy)
+	}
+	if s.Dedupe.MaxDistance < 0 || s.Dedupe.MaxDistance > 64 {
+		return fmt.Errorf("dedupe.max_distance должно быть в диапазоне 0..64, получено %d", s.Dedupe.MaxDistance)
+	}
+	switch s.Blank.Policy {
+	case BlankKeep, BlankRemove, BlankParity:
+	default:
+		return fmt.Errorf("blank.policy: неизвестная политика %q", s.Blank.Policy)
+	}
+	if s.Blank.MinUniformShare < 0 || s.Blank.MinUniformShare > 1 {
+		return fmt.Errorf("blank.min_uniform_share должно быть в диапазоне 0..1, получено %v", s.Blank.MinUniformShare)
+	}
+	if s.Crop.MinContentRatio < 0 || s.Crop.MinContentRatio > 1 {
+		return fmt.Errorf("crop.min_content_ratio должно быть в диапазоне 0..1, получено %v", s.Crop.MinContentRatio)
+	}
+	if len(s.Profiles) == 0 {
+		return errors.New("не задан ни один профиль вывода")
+	}
+	names := map[string]bool{}
+	for _, p := range s.Profiles {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("профиль %q: %w", p.Name, err)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("профиль %q указан дважды", p.Name)
+		}
+		names[p.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSettingsMissingFile(t *testing.T) {
+	cfg, err := LoadSettings(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("LoadSettings error: %v", err)
+	}
+	if cfg.JPEGQuality != DefaultSettings().JPEGQuality {
+		t.Fatalf("expected defaults, got quality %d", cfg.JPEGQuality)
+	}
+}
+
+func TestLoadSettingsKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"crop":{"enabled":true}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings error: %v", err)
+	}
+	if !cfg.Crop.Enabled {
+		t.Fatal("crop.enabled should be true")
+	}
+	if cfg.Crop.Tolerance != DefaultSettings().Crop.Tolerance {
+		t.Fatalf("crop.tolerance = %d, want default", cfg.Crop.Tolerance)
+	}
+}
+
+func TestLoadSettingsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"jpeg_quality":0}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := LoadSettings(path); err == nil {
+		t.Fatal("expected validation error")
+	}
+}
+
+func TestLoadSettingsProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"profiles":[{"name":"kindle","spreads":"split"},{"name":"tablet","reading_direction":"ltr"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings error: %v", err)
+	}
+	if len(cfg.Profiles) != 2 {
+		t.Fatalf("got %d profiles, want 2", len(cfg.Profiles))
+	}
+	kindle := cfg.Profiles[0]
+	if kindle.Format != "cbz" || kindle.ReadingDirection != DirectionRTL || kindle.Spreads != SpreadSplit {
+		t.Fatalf("unexpected kindle profile: %+v", kindle)
+	}
+	if cfg.Profiles[1].Spreads != SpreadKeep {
+		t.Fatalf("tablet spreads = %q, want keep", cfg.Profiles[1].Spreads)
+	}
+}
+
+func TestLoadSettingsBadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"profiles":[{"spreads":"fold"}]}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := LoadSettings(path); err == nil {
+		t.Fatal("expected validation error for unknown spread mode")
+	}
+}
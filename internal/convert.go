@@ -1,10 +1,13 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -45,12 +48,19 @@ func ProcessZip(name string) error {
 		return fmt.Errorf("не найдена валидная папка с мангой в архиве %s", name)
 	}
 
-	mangaName := filepath.Base(mangaRoot)
+	// Release tags in the names would only hurt the provider search.
+	mangaName := seriesName(filepath.Base(mangaRoot))
 	log.Printf("🔍 Получение метаданных для: %s", mangaName)
-	meta, err := FetchMetadata(mangaName)
+	meta, err := LookupMetadata(mangaName)
 	if err != nil {
 		log.Printf("⚠️ Не удалось получить метаданные, продолжаем без них: %v", err)
-		meta = &Metadata{Title: mangaName}
+		meta = newMetadata(Series{}, mangaName)
+	}
+	meta.CoverFile = seriesCover(meta, mangaName, workPath)
+	release := parseRelease(strings.TrimSuffix(name, ".zip"), filepath.Base(mangaRoot))
+	meta.Group, meta.ReleaseTags = release.Group, release.Tags
+	if release.Group != "" {
+		log.Printf("👥 Группа перевода: %s", release.Group)
 	}
 
 	entries, err := os.ReadDir(mangaRoot)
@@ -58,50 +68,246 @@ func ProcessZip(name string) error {
 		return fmt.Errorf("чтение манга-корня %s: %w", mangaRoot, err)
 	}
 
+	var volumes []string
 	for _, entry := range entries {
 		if entry.IsDir() {
 			volPath := filepath.Join(mangaRoot, entry.Name())
 			if ContainsImages(volPath) {
-				err := convertVolume(volPath, entry.Name(), mangaRoot, meta)
-				if err != nil {
-					log.Printf("❌ Ошибка тома %s: %v", entry.Name(), err)
-				} else {
-					log.Printf("✅ Том %s успешно обработан", entry.Name())
-				}
+				volumes = append(volumes, entry.Name())
 			} else {
 				log.Printf("⏭ Пропущен каталог (нет изображений): %s", volPath)
 			}
 		}
 	}
 
+	slices.SortFunc(volumes, compareVolumeNames)
+
+	report := NewJobReport(name)
+	if Config.Accumulate.Enabled {
+		// In accumulation mode the folders of the archive are chapters.
+		// The archive is kept when staging fails so no chapter is lost.
+		if err := accumulateChapters(mangaRoot, volumes, meta, report); err != nil {
+			return fmt.Errorf("накопление глав: %w", err)
+		}
+	} else if Config.Granularity == GranularityOmnibus {
+		for _, group := range omnibusGroups(volumes, Config.OmnibusVolumes) {
+			label := omnibusLabel(group, len(group) == len(volumes))
+			volReport := report.Volume(label)
+			if err := convertOmnibus(mangaRoot, group, meta, volReport, len(group) == len(volumes)); err != nil {
+				volReport.Error = err.Error()
+				log.Printf("❌ Ошибка сборника %s: %v", label, err)
+			} else {
+				log.Printf("✅ Сборник %s успешно обработан", label)
+			}
+		}
+	} else {
+		for _, volume := range volumes {
+			volReport := report.Volume(volume)
+			err := convertVolume(filepath.Join(mangaRoot, volume), volume, mangaRoot, meta, volReport)
+			if err != nil {
+				volReport.Error = err.Error()
+				log.Printf("❌ Ошибка тома %s: %v", volume, err)
+			} else {
+				log.Printf("✅ Том %s успешно обработан", volume)
+			}
+		}
+	}
+
+	if out, err := report.Save(filepath.Join("output", "reports")); err != nil {
+		log.Printf("⚠️ Не удалось сохранить отчёт: %v", err)
+	} else {
+		log.Printf("📝 Отчёт: %s", out)
+	}
+
 	deferCleanup()
 
 	return nil
 }
 
-func convertVolume(volumePath string, volumeName string, mangaRoot string, meta *Metadata) error {
-	mangaName := filepath.Base(mangaRoot)
-	cbzDir := filepath.Join("output/cbz", meta.Title)
-	os.MkdirAll(cbzDir, os.ModePerm)
-	outputBase := SafeName(fmt.Sprintf("%s__%s", mangaName, volumeName))
+func convertVolume(volumePath string, volumeName string, mangaRoot string, meta *Metadata, report *VolumeReport) error {
+	mangaName := seriesName(filepath.Base(mangaRoot))
+	pages, err := LoadPages(volumePath)
+	if err != nil {
+		return fmt.Errorf("чтение страниц: %w", err)
+	}
+	return convertPages(pages, volumeName, mangaName, meta, report)
+}
+
+// convertPages processes the loaded pages of a volume and writes its books.
+func convertPages(pages []*Page, volumeName string, mangaName string, meta *Metadata, report *VolumeReport) error {
+	// Release tags of the volume folder add to those of the archive.
+	release := parseRelease(volumeName)
+	seriesMeta := *meta
+	seriesMeta.ReleaseTags = mergeTags(slices.Clone(meta.ReleaseTags), release.Tags...)
+	if seriesMeta.Group == "" {
+		seriesMeta.Group = release.Group
+	}
 
-	volumeMeta := *meta
-	volumeMeta.Title = fmt.Sprintf("%s — Том %s", meta.Title, volumeName)
+	info := parseVolumeName(volumeName)
+	volumeMeta := seriesMeta
+	volumeMeta.Title = fmt.Sprintf("%s — %s", meta.Series.Title, volumeTitle(volumeName))
+	volumeMeta.Volume = info.Volume
+	volumeMeta.Number = info.ChapterRange()
 
-	cbzOut := filepath.Join(cbzDir, outputBase+".cbz")
-	// epubOut := filepath.Join("output/epub", outputBase+".epub")
+	pages, err := ProcessPages(pages, report)
+	if err != nil {
+		return fmt.Errorf("обработка страниц: %w", err)
+	}
 
-	if err := CreateCBZ(volumePath, &volumeMeta, cbzOut); err != nil {
-		return fmt.Errorf("ошибка CBZ: %w", err)
+	if Config.Granularity == GranularityChapter {
+		done, err := convertChapters(pages, volumeName, mangaName, &seriesMeta, report)
+		if done || err != nil {
+			return err
+		}
+		log.Printf("⚠️ Том %s: главы не найдены, собираем том целиком", volumeName)
 	}
 
-	// if err := CreateEPUB(volumePath, &volumeMeta, epubOut); err != nil {
-	// 	return fmt.Errorf("ошибка EPUB: %w", err)
-	// }
+	name := bookName{Folder: mangaName, Label: volumeFileLabel(volumeName)}
+	return writeOutputs(pages, &volumeMeta, name, report)
+}
 
+// bookName carries what the naming templates need besides the metadata.
+type bookName struct {
+	Folder string // manga folder name without release tags
+	Label  string // "Vol_01", "Vol_02__Chapter_004", "Omnibus"
+}
+
+// writeOutputs writes a book once per output profile. A book whose pages
+// were all removed (blank, duplicates, skipped as broken) is an error rather
+// than a silent success without output.
+func writeOutputs(pages []*Page, meta *Metadata, name bookName, report *VolumeReport) error {
+	if len(pages) == 0 {
+		return errors.New("после обработки не осталось страниц")
+	}
+	for _, profile := range Config.Profiles {
+		outputs, err := writeProfile(profile, pages, meta, name)
+		if err != nil {
+			return fmt.Errorf("профиль %s: %w", profile.Name, err)
+		}
+		report.Outputs = append(report.Outputs, outputs...)
+	}
 	return nil
 }
 
+func writeProfile(profile OutputProfile, pages []*Page, meta *Metadata, name bookName) ([]string, error) {
+	tmpDir, err := os.MkdirTemp("", "manga-converter-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pages, err = profilePages(pages, profile, tmpDir)
+	if err != nil {
+		return nil, fmt.Errorf("обработка страниц: %w", err)
+	}
+
+	pages, err = prependCover(pages, meta.CoverFile)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := splitParts(pages, profile.Parts)
+	if err != nil {
+		return nil, fmt.Errorf("разбиение на части: %w", err)
+	}
+	if len(parts) > 1 {
+		log.Printf("✂️ Том разбит на %d частей", len(parts))
+	}
+
+	var outputs []string
+	for i, part := range parts {
+		partMeta := *meta
+		partNumber := ""
+		if len(parts) > 1 {
+			partMeta.Title = fmt.Sprintf("%s, часть %d", meta.Title, i+1)
+			partNumber = strconv.Itoa(i + 1)
+		}
+		outDir, base, err := outputPath(profile, &partMeta, name, partNumber)
+		if err != nil {
+			return nil, fmt.Errorf("шаблон имени: %w", err)
+		}
+		os.MkdirAll(outDir, os.ModePerm)
+		writeSeriesCover(outDir, meta.CoverFile)
+		if Config.SeriesJSON && profile.Format == "cbz" {
+			if err := writeSeriesJSON(outDir, meta.Series); err != nil {
+				log.Printf("⚠️ Не удалось обновить %s: %v", filepath.Join(outDir, seriesJSONName), err)
+			}
+		}
+		out, err := writeBook(profile, part, &partMeta, outDir, base)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
+
+// outputPath renders the profile naming templates into the output folder and
+// the book name without extension.
+func outputPath(profile OutputProfile, meta *Metadata, name bookName, part string) (string, string, error) {
+	year := ""
+	if meta.Series.Year > 0 {
+		year = strconv.Itoa(meta.Series.Year)
+	}
+	values := map[string]string{
+		"series":  meta.Series.Title,
+		"folder":  name.Folder,
+		"book":    name.Label,
+		"volume":  meta.Volume,
+		"chapter": meta.Number,
+		"year":    year,
+		"group":   meta.Group,
+		"format":  profile.Format,
+		"profile": profile.Name,
+		"part":    part,
+	}
+	naming := profile.naming()
+	if naming.Charset == CharsetASCII {
+		// Kanji have no ASCII form; prefer a title that keeps every letter.
+		titles := meta.Series.Titles
+		values["series"] = asciiName(meta.Series.Title, titles.Romaji, titles.English, name.Folder)
+		values["folder"] = asciiName(name.Folder, titles.Romaji, titles.English, meta.Series.Title)
+	}
+	// A title such as "Fate/Zero" must not create nested folders.
+	for k, v := range values {
+		values[k] = strings.NewReplacer("/", "_", `\`, "_").Replace(v)
+	}
+
+	dir, err := renderTemplate(naming.Dir, values)
+	if err != nil {
+		return "", "", err
+	}
+	file, err := renderTemplate(naming.File, values)
+	if err != nil {
+		return "", "", err
+	}
+	parts := append([]string{"output"}, sanitizePath(dir, naming.Charset)...)
+	return filepath.Join(parts...), sanitizeName(strings.ReplaceAll(file, " ", "_"), naming.Charset), nil
+}
+
+// writeBook packs pages into outDir/<base>.<format>.
+func writeBook(profile OutputProfile, pages []*Page, meta *Metadata, outDir string, base string) (string, error) {
+	out := filepath.Join(outDir, base+"."+profile.Format)
+
+	switch profile.Format {
+	case "epub":
+		if err := CreateEPUB(pages, meta, profile, out); err != nil {
+			return "", fmt.Errorf("ошибка EPUB: %w", err)
+		}
+	default:
+		if err := CreateCBZ(pages, meta, out); err != nil {
+			return "", fmt.Errorf("ошибка CBZ: %w", err)
+		}
+	}
+
+	if profile.Panels {
+		if err := writePanelSidecar(pages, filepath.Join(outDir, base+".panels.json")); err != nil {
+			log.Printf("⚠️ Не удалось сохранить кадры: %v", err)
+		}
+	}
+	return out, nil
+}
+
 func IsZip(name string) bool {
 	return strings.HasSuffix(name, ".zip")
 }
@@ -58,10 +58,23 @@ func TestProcessZip(t *testing.T) {
 	zipPath := filepath.Join(inputDir, "test.zip")
 	zipDirectory(t, filepath.Join(sourceRoot, "TestManga"), zipPath)
 
+	coverPath := filepath.Join(tmp, "cover.jpg")
+	writeJPEG(t, coverPath, 20, 30)
+	cover, err := os.ReadFile(coverPath)
+	if err != nil {
+		t.Fatalf("read cover: %v", err)
+	}
 	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
 		if req.URL.Host != "shikimori.one" {
 			t.Fatalf("unexpected host: %s", req.URL.Host)
 		}
+		if req.URL.Path == "/covers/1.jpg" {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(cover)),
+				Header:     make(http.Header),
+			}, nil
+		}
 		if req.URL.Query().Get("search") != "TestManga" {
 			t.Fatalf("unexpected search value: %s", req.URL.Query().Get("search"))
 		}
@@ -70,9 +83,7 @@ func TestProcessZip(t *testing.T) {
 			URL:         "/mangas/1",
 			Description: "Test description",
 			Genres:      []string{"Action", "Drama"},
-			Image: struct {
-				Original string `json:"original"`
-			}{Original: "/covers/1.jpg"},
+			Image:       shikimoriImage{Original: "/covers/1.jpg"},
 		}}
 		body, err := json.Marshal(payload)
 		if err != nil {
@@ -96,11 +107,33 @@ func TestProcessZip(t *testing.T) {
 		t.Fatalf("workdir should be cleaned: %v", err)
 	}
 
-	cbzPath := filepath.Join("output", "cbz", "Test Title", "TestManga__Volume_1.cbz")
+	reportData, err := os.ReadFile(filepath.Join("output", "reports", "test.json"))
+	if err != nil {
+		t.Fatalf("expected job report: %v", err)
+	}
+	var report JobReport
+	if err := json.Unmarshal(reportData, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(report.Volumes) != 1 || report.Volumes[0].Name != "Volume 1" || len(report.Volumes[0].Outputs) != 1 {
+		t.Fatalf("unexpected report: %s", reportData)
+	}
+
+	cbzPath := filepath.Join("output", "cbz", "Test Title", "TestManga__Vol_01.cbz")
 	if _, err := os.Stat(cbzPath); err != nil {
 		t.Fatalf("expected CBZ at %s: %v", cbzPath, err)
 	}
 
+	if meta := readSeriesJSON(t, filepath.Join("output", "cbz", "Test Title")); meta["name"] != "Test Title" {
+		t.Fatalf("series.json name = %v", meta["name"])
+	}
+	for _, name := range []string{"cover.jpg", "folder.jpg"} {
+		data, err := os.ReadFile(filepath.Join("output", "cbz", "Test Title", name))
+		if err != nil || !bytes.Equal(data, cover) {
+			t.Fatalf("series folder should hold the provider cover as %s: %v", name, err)
+		}
+	}
+
 	r, err := zip.OpenReader(cbzPath)
 	if err != nil {
 		t.Fatalf("open cbz: %v", err)
@@ -120,12 +153,25 @@ func TestProcessZip(t *testing.T) {
 			if err != nil {
 				t.Fatalf("read ComicInfo: %v", err)
 			}
-			if !bytes.Contains(data, []byte("Test Title — Том Volume 1")) {
+			if !bytes.Contains(data, []byte("Test Title — Том 1")) {
 				t.Fatalf("ComicInfo.xml missing title, got %s", data)
 			}
+			if !bytes.Contains(data, []byte(`<Page Image="0" ImageWidth="10" ImageHeight="10">`)) {
+				t.Fatalf("ComicInfo.xml missing page dimensions, got %s", data)
+			}
 		}
 	}
 	if !hasComicInfo {
 		t.Fatal("ComicInfo.xml not found in CBZ")
 	}
 }
+
+func TestWriteOutputsWithoutPages(t *testing.T) {
+	report := &VolumeReport{}
+	if err := writeOutputs(nil, &Metadata{}, bookName{Folder: "Test", Label: "Vol_01"}, report); err == nil {
+		t.Fatal("a book without pages should be an error")
+	}
+	if len(report.Outputs) != 0 {
+		t.Fatalf("no output expected, got %v", report.Outputs)
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+type CoverSettings struct {
+	// Enabled downloads the provider cover once per series.
+	Enabled bool `json:"enabled"`
+	// SeriesFiles writes cover.jpg and folder.jpg into the series output
+	// folder unless they already exist.
+	SeriesFiles bool `json:"series_files"`
+	// Prepend adds the cover as the first page of books whose first page
+	// is not a cover.
+	Prepend bool `json:"prepend"`
+	// MinColorfulness is the mean chroma (0-1) from which a portrait first
+	// page counts as a colour cover; grey scans stay near 0.
+	MinColorfulness float64 `json:"min_colorfulness"`
+}
+
+// coverPageName sorts before any page name in readers that order by name.
+const coverPageName = "!cover.jpg"
+
+// seriesCoverFiles are the names Komga, Kavita and file managers look for.
+var seriesCoverFiles = []string{"cover.jpg", "folder.jpg"}
+
+// seriesCover returns the cover of a series: the cover.jpg already in one of
+// its output folders or, failing that, a fresh download into dir. Chapter
+// archives of a series in the library thus do not fetch it again.
+func seriesCover(meta *Metadata, folder, dir string) string {
+	if !Config.Cover.Enabled {
+		return ""
+	}
+	for _, profile := range Config.Profiles {
+		outDir, _, err := outputPath(profile, meta, bookName{Folder: folder}, "")
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(outDir, seriesCoverFiles[0])
+		if _, err := os.Stat(path); err == nil {
+			log.Printf("🖼 Обложка серии уже есть: %s", path)
+			return path
+		}
+	}
+	return downloadCover(meta.Series, dir)
+}
+
+// downloadCover saves the largest cover variant that downloads and decodes
+// as dir/cover.jpg. Failures only cost the cover, so they are logged and ""
+// is returned.
+func downloadCover(series Series, dir string) string {
+	if !Config.Cover.Enabled {
+		return ""
+	}
+	for _, cover := range series.Covers {
+		path := filepath.Join(dir, "cover.jpg")
+		if err := fetchCoverImage(cover.URL, path); err != nil {
+			log.Printf("⚠️ Обложка %s не загружена: %v", cover.URL, err)
+			continue
+		}
+		log.Printf("🖼 Обложка серии загружена: %s", cover.URL)
+		return path
+	}
+	return ""
+}
+
+func fetchCoverImage(url, path string) error {
+	part := path + ".part"
+	defer os.Remove(part)
+	if err := DownloadFile(url, part); err != nil {
+		return err
+	}
+	img, format, err := decodeImage(part)
+	if err != nil {
+		return fmt.Errorf("декодирование: %w", err)
+	}
+	if format == "jpeg" {
+		// Keep the provider file as is instead of recompressing it.
+		return os.Rename(part, path)
+	}
+	return encodeImage(path, img)
+}
+
+// writeSeriesCover copies the series cover into an output folder. Existing
+// files are left alone so hand-picked artwork survives new volumes.
+func writeSeriesCover(dir, cover string) {
+	if cover == "" || !Config.Cover.SeriesFiles {
+		return
+	}
+	for _, name := range seriesCoverFiles {
+		target := filepath.Join(dir, name)
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := copyFile(cover, target); err != nil {
+			log.Printf("⚠️ Не удалось записать %s: %v", target, err)
+		}
+	}
+}
+
+// prependCover puts the series cover in front of a book whose first page
+// is not a colour portrait page.
+func prependCover(pages []*Page, cover string) ([]*Page, error) {
+	if cover == "" || !Config.Cover.Prepend || len(pages) == 0 {
+		return pages, nil
+	}
+	has, err := hasCoverPage(pages[0])
+	if err != nil || has {
+		return pages, err
+	}
+	img, _, err := decodeImage(cover)
+	if err != nil {
+		return nil, fmt.Errorf("обложка: %w", err)
+	}
+	b := img.Bounds()
+	page := &Page{
+		Path: cover, Name: coverPageName,
+		Width: b.Dx(), Height: b.Dy(), OriginalWidth: b.Dx(), OriginalHeight: b.Dy(),
+		FrontCover: true,
+	}
+	log.Printf("🖼 Первая страница не похожа на обложку, добавлена обложка серии")
+	return append([]*Page{page}, pages...), nil
+}
+
+func hasCoverPage(p *Page) (bool, error) {
+	if p.Problem != "" {
+		return false, nil
+	}
+	if p.Width > p.Height {
+		return false, nil
+	}
+	img, _, err := decodeImage(p.Path)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", p.Name, err)
+	}
+	return colorfulness(img) >= Config.Cover.MinColorfulness, nil
+}
+
+// colorfulness is the mean chroma (max-min of RGB) of a pixel sample, 0-1.
+func colorfulness(img image.Image) float64 {
+	b := img.Bounds()
+	step := max(1, min(b.Dx(), b.Dy())/100)
+	var sum float64
+	n := 0
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			sum += float64(max(r, g, bl)-min(r, g, bl)) / 0xffff
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"image"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrependCover(t *testing.T) {
+	original := Config
+	settings := *Config
+	settings.Cover.Prepend = true
+	Config = &settings
+	t.Cleanup(func() { Config = original })
+
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.jpg")
+	writeJPEG(t, cover, 20, 30)
+	gray := filepath.Join(dir, "001.png")
+	writeImageFile(t, gray, framedPage(20, 30, image.Rect(5, 5, 15, 25)))
+	colour := filepath.Join(dir, "002.jpg")
+	writeJPEG(t, colour, 20, 30)
+
+	pages, err := prependCover([]*Page{{Path: gray, Name: "001.png", Width: 20, Height: 30}}, cover)
+	if err != nil {
+		t.Fatalf("prependCover error: %v", err)
+	}
+	if len(pages) != 2 || !pages[0].FrontCover || pages[0].Name != coverPageName || pages[0].Width != 20 {
+		t.Fatalf("grey first page should get the cover in front, got %+v", pages[0])
+	}
+
+	pages, err = prependCover([]*Page{{Path: colour, Name: "002.jpg", Width: 20, Height: 30}}, cover)
+	if err != nil {
+		t.Fatalf("prependCover error: %v", err)
+	}
+	if len(pages) != 1 {
+		t.Fatal("colour first page is already a cover")
+	}
+
+	data, err := buildComicInfo([]*Page{{Name: coverPageName, FrontCover: true}}, &Metadata{})
+	if err != nil {
+		t.Fatalf("buildComicInfo error: %v", err)
+	}
+	if !strings.Contains(string(data), `<Page Image="0" Type="FrontCover">`) {
+		t.Fatalf("ComicInfo.xml missing FrontCover page, got %s", data)
+	}
+}
+
+func TestWriteSeriesCover(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(t.TempDir(), "cover.jpg")
+	writeJPEG(t, cover, 20, 30)
+	manual := filepath.Join(dir, "folder.jpg")
+	if err := os.WriteFile(manual, []byte("manual"), 0o644); err != nil {
+		t.Fatalf("write folder.jpg: %v", err)
+	}
+
+	writeSeriesCover(dir, cover)
+	if _, err := os.Stat(filepath.Join(dir, "cover.jpg")); err != nil {
+		t.Fatalf("cover.jpg not written: %v", err)
+	}
+	if data, _ := os.ReadFile(manual); string(data) != "manual" {
+		t.Fatal("existing folder.jpg must not be overwritten")
+	}
+}
+
+func TestSeriesCoverReusesOutputFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("cover already in the library should not be downloaded, got %s", req.URL)
+		return nil, nil
+	}))
+
+	meta := newMetadata(Series{Titles: Titles{Russian: "Ван-Пис"}, Covers: []Cover{{URL: "https://example.com/cover.jpg"}}}, "One Piece")
+	existing := filepath.Join("output", "cbz", "Ван-Пис", "cover.jpg")
+	writeJPEG(t, existing, 20, 30)
+
+	if got := seriesCover(meta, "One Piece", t.TempDir()); got != existing {
+		t.Fatalf("seriesCover = %q, want %q", got, existing)
+	}
+}
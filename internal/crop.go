@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"image"
+	"log"
+	"sort"
+)
+
+// cropNoise is the share of pixels in a border line allowed to deviate from
+// the border colour (dust, JPEG artefacts, scanner noise).
+const cropNoise = 0.005
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// CropPage removes uniform white or black margins from a page in place.
+func CropPage(p *Page, cfg CropSettings) error {
+	img, _, err := decodeImage(p.Path)
+	if err != nil {
+		return err
+	}
+
+	b := img.Bounds()
+	rect := contentBounds(img, cfg.Tolerance)
+	if rect == b {
+		return nil
+	}
+
+	ratio := float64(rect.Dx()*rect.Dy()) / float64(b.Dx()*b.Dy())
+	if rect.Empty() || ratio < cfg.MinContentRatio {
+		log.Printf("⏭ Обрезка пропущена для %s: осталось бы %.0f%% страницы", p.Name, ratio*100)
+		return nil
+	}
+
+	sub, ok := img.(subImager)
+	if !ok {
+		return nil
+	}
+	if err := encodeImage(p.Path, sub.SubImage(rect)); err != nil {
+		return err
+	}
+
+	log.Printf("✂️ Обрезка %s: %dx%d → %dx%d", p.Name, b.Dx(), b.Dy(), rect.Dx(), rect.Dy())
+	p.Width, p.Height = rect.Dx(), rect.Dy()
+	return nil
+}
+
+// contentBounds finds the rectangle left after stripping uniform lines from
+// every side. Each side is compared against its own outermost line.
+func contentBounds(img image.Image, tolerance uint8) image.Rectangle {
+	b := img.Bounds()
+	if b.Empty() {
+		return b
+	}
+
+	row := func(y int) []uint8 {
+		line := make([]uint8, 0, b.Dx())
+		for x := b.Min.X; x < b.Max.X; x++ {
+			line = append(line, luma(img, x, y))
+		}
+		return line
+	}
+
+	top, bottom := b.Min.Y, b.Max.Y
+	ref := medianLuma(row(top))
+	for top < bottom && uniformLine(row(top), ref, tolerance) {
+		top++
+	}
+	ref = medianLuma(row(bottom - 1))
+	for bottom > top && uniformLine(row(bottom-1), ref, tolerance) {
+		bottom--
+	}
+	if top >= bottom {
+		return image.Rectangle{}
+	}
+
+	// Columns are only compared inside the rows that survived, so a white
+	// top margin does not hide a black side margin.
+	col := func(x int) []uint8 {
+		line := make([]uint8, 0, bottom-top)
+		for y := top; y < bottom; y++ {
+			line = append(line, luma(img, x, y))
+		}
+		return line
+	}
+
+	left, right := b.Min.X, b.Max.X
+	ref = medianLuma(col(left))
+	for left < right && uniformLine(col(left), ref, tolerance) {
+		left++
+	}
+	ref = medianLuma(col(right - 1))
+	for right > left && uniformLine(col(right-1), ref, tolerance) {
+		right--
+	}
+	if left >= right {
+		return image.Rectangle{}
+	}
+
+	return image.Rect(left, top, right, bottom)
+}
+
+func uniformLine(line []uint8, ref, tolerance uint8) bool {
+	allowed := int(float64(len(line)) * cropNoise)
+	deviating := 0
+	for _, v := range line {
+		if absDiff(v, ref) > tolerance {
+			deviating++
+			if deviating > allowed {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func medianLuma(line []uint8) uint8 {
+	sorted := append([]uint8(nil), line...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+)
+
+func TestContentBounds(t *testing.T) {
+	content := image.Rect(10, 5, 30, 40)
+	img := framedPage(50, 60, content)
+
+	if got := contentBounds(img, 16); got != content {
+		t.Fatalf("contentBounds = %v, want %v", got, content)
+	}
+}
+
+func TestContentBoundsBlankPage(t *testing.T) {
+	img := framedPage(20, 20, image.Rectangle{})
+
+	if got := contentBounds(img, 16); !got.Empty() {
+		t.Fatalf("contentBounds of blank page = %v, want empty", got)
+	}
+}
+
+func TestCropPage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "001.png")
+	writeImageFile(t, path, framedPage(100, 80, image.Rect(10, 10, 90, 70)))
+
+	page := &Page{Path: path, Name: "001.png", Width: 100, Height: 80, OriginalWidth: 100, OriginalHeight: 80}
+	if err := CropPage(page, CropSettings{Enabled: true, Tolerance: 16, MinContentRatio: 0.5}); err != nil {
+		t.Fatalf("CropPage error: %v", err)
+	}
+
+	if page.Width != 80 || page.Height != 60 {
+		t.Fatalf("page size = %dx%d, want 80x60", page.Width, page.Height)
+	}
+	if page.OriginalWidth != 100 || page.OriginalHeight != 80 {
+		t.Fatalf("original size = %dx%d, want 100x80", page.OriginalWidth, page.OriginalHeight)
+	}
+	w, h, err := ImageSize(path)
+	if err != nil {
+		t.Fatalf("ImageSize error: %v", err)
+	}
+	if w != 80 || h != 60 {
+		t.Fatalf("file size = %dx%d, want 80x60", w, h)
+	}
+}
+
+func TestCropPageRespectsMinContentRatio(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "001.png")
+	writeImageFile(t, path, framedPage(100, 100, image.Rect(40, 40, 60, 60)))
+
+	page := &Page{Path: path, Name: "001.png", Width: 100, Height: 100}
+	if err := CropPage(page, CropSettings{Enabled: true, Tolerance: 16, MinContentRatio: 0.5}); err != nil {
+		t.Fatalf("CropPage error: %v", err)
+	}
+	if page.Width != 100 || page.Height != 100 {
+		t.Fatalf("page should stay untouched, got %dx%d", page.Width, page.Height)
+	}
+}
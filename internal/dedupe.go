@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type DedupeSettings struct {
+	Enabled bool `json:"enabled"`
+	// Blocklist is a text file with one perceptual hash (16 hex digits) per
+	// line, optionally followed by a comment. Lines starting with # are
+	// ignored. Hashes can be obtained with `converter hash <image>`.
+	Blocklist string `json:"blocklist"`
+	// MaxDistance is the largest Hamming distance between a page hash and a
+	// blocklist hash that still counts as a match.
+	MaxDistance int `json:"max_distance"`
+}
+
+type blockedHash struct {
+	hash  uint64
+	label string
+}
+
+// RemoveDuplicates drops exact duplicates within a volume (by SHA-256) and
+// pages whose perceptual hash is close to a blocklisted one.
+func RemoveDuplicates(pages []*Page, cfg DedupeSettings, report *VolumeReport) ([]*Page, error) {
+	blocklist, err := LoadBlocklist(cfg.Blocklist)
+	if err != nil {
+		return nil, fmt.Errorf("чёрный список %s: %w", cfg.Blocklist, err)
+	}
+
+	sums := make([]string, len(pages))
+	hashes := make([]uint64, len(pages))
+	err = forEachPage(pages, func(i int, p *Page) error {
+		if p.Problem != "" {
+			return nil
+		}
+		sum, err := fileSHA256(p.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		sums[i] = sum
+		if len(blocklist) > 0 {
+			img, _, err := decodeImage(p.Path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", p.Name, err)
+			}
+			hashes[i] = PerceptualHash(img)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]string{}
+	out := make([]*Page, 0, len(pages))
+	for i, p := range pages {
+		if p.Problem != "" {
+			out = append(out, p)
+			continue
+		}
+
+		if first, ok := seen[sums[i]]; ok {
+			removePage(p, report, "duplicate", "совпадает с "+first)
+			continue
+		}
+		seen[sums[i]] = p.Name
+
+		if len(blocklist) > 0 {
+			hash := hashes[i]
+			if b, dist, ok := matchBlocklist(hash, blocklist, cfg.MaxDistance); ok {
+				removePage(p, report, "blocklisted", fmt.Sprintf("%016x ~ %016x (%s), расстояние %d", hash, b.hash, b.label, dist))
+				continue
+			}
+		}
+
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func removePage(p *Page, report *VolumeReport, problem, detail string) {
+	log.Printf("🗑 Удалена страница %s: %s (%s)", p.Name, problem, detail)
+	report.AddFinding(Finding{Page: p.Name, Problem: problem, Detail: detail, Action: "removed"})
+}
+
+func matchBlocklist(hash uint64, blocklist []blockedHash, maxDistance int) (blockedHash, int, bool) {
+	for _, b := range blocklist {
+		if dist := bits.OnesCount64(hash ^ b.hash); dist <= maxDistance {
+			return b, dist, true
+		}
+	}
+	return blockedHash{}, 0, false
+}
+
+// LoadBlocklist reads the perceptual hash blocklist. A missing file is an
+// empty list.
+func LoadBlocklist(path string) ([]blockedHash, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var list []blockedHash
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		hash, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: неверный хеш %q", n, fields[0])
+		}
+		label := strings.TrimSpace(strings.TrimPrefix(strings.Join(fields[1:], " "), "#"))
+		list = append(list, blockedHash{hash: hash, label: label})
+	}
+	return list, scanner.Err()
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// PerceptualHash computes a 64-bit difference hash: the image is reduced to a
+// 9x8 luminance grid and every bit tells whether a cell is brighter than its
+// right neighbour. Re-encoded or slightly resized copies of a page end up
+// within a few bits of each other.
+func PerceptualHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	var grid [h][w]uint64
+	var counts [h][w]uint64
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		gy := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			gx := (x - b.Min.X) * w / b.Dx()
+			grid[gy][gx] += uint64(luma(img, x, y))
+			counts[gy][gx]++
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			left := grid[y][x] * counts[y][x+1]
+			right := grid[y][x+1] * counts[y][x]
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashFile returns the perceptual hash of an image file in blocklist format.
+func HashFile(path string) (string, error) {
+	img, _, err := decodeImage(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x", PerceptualHash(img)), nil
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// patternPage draws a few random grey blocks, giving pages that are easy to
+// tell apart by perceptual hash.
+func patternPage(seed int64) *image.Gray {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewGray(image.Rect(0, 0, 90, 120))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	for n := 0; n < 8; n++ {
+		x, y := rng.Intn(70), rng.Intn(100)
+		rect := image.Rect(x, y, x+10+rng.Intn(30), y+10+rng.Intn(40))
+		shade := color.Gray{Y: uint8(rng.Intn(256))}
+		for py := rect.Min.Y; py < rect.Max.Y; py++ {
+			for px := rect.Min.X; px < rect.Max.X; px++ {
+				img.SetGray(px, py, shade)
+			}
+		}
+	}
+	return img
+}
+
+func TestPerceptualHashStableAcrossEncodings(t *testing.T) {
+	img := patternPage(1)
+	dir := t.TempDir()
+	pngPath := filepath.Join(dir, "page.png")
+	jpgPath := filepath.Join(dir, "page.jpg")
+	writeImageFile(t, pngPath, img)
+	writeImageFile(t, jpgPath, img)
+
+	a, _, err := decodeImage(pngPath)
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	b, _, err := decodeImage(jpgPath)
+	if err != nil {
+		t.Fatalf("decode jpg: %v", err)
+	}
+	maxDistance := DefaultSettings().Dedupe.MaxDistance
+	if dist := bits.OnesCount64(PerceptualHash(a) ^ PerceptualHash(b)); dist > maxDistance {
+		t.Fatalf("re-encoded page is too far: distance %d", dist)
+	}
+
+	other := PerceptualHash(patternPage(2))
+	if dist := bits.OnesCount64(PerceptualHash(img) ^ other); dist < 10 {
+		t.Fatalf("different pages are too close: distance %d", dist)
+	}
+}
+
+func TestRemoveDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	credits := patternPage(10)
+	writeImageFile(t, filepath.Join(dir, "001.png"), patternPage(11))
+	writeImageFile(t, filepath.Join(dir, "002.png"), patternPage(12))
+	writeImageFile(t, filepath.Join(dir, "003.png"), patternPage(12))
+	// The blocklisted credits page re-encoded as JPEG.
+	writeImageFile(t, filepath.Join(dir, "004.jpg"), credits)
+
+	blocklist := filepath.Join(dir, "blocklist.txt")
+	line := "# scanlator credits\n" + fmt.Sprintf("%016x", PerceptualHash(credits)) + " # credits\n"
+	if err := os.WriteFile(blocklist, []byte(line), 0o644); err != nil {
+		t.Fatalf("write blocklist: %v", err)
+	}
+
+	pages, err := LoadPages(dir)
+	if err != nil {
+		t.Fatalf("LoadPages error: %v", err)
+	}
+	report := &VolumeReport{}
+	got, err := RemoveDuplicates(pages, DedupeSettings{Enabled: true, Blocklist: blocklist, MaxDistance: 6}, report)
+	if err != nil {
+		t.Fatalf("RemoveDuplicates error: %v", err)
+	}
+
+	if len(got) != 2 || got[0].Name != "001.png" || got[1].Name != "002.png" {
+		names := []string{}
+		for _, p := range got {
+			names = append(names, p.Name)
+		}
+		t.Fatalf("kept pages %v, want [001.png 002.png]", names)
+	}
+	if len(report.Findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", report.Findings)
+	}
+	if report.Findings[0].Problem != "duplicate" || report.Findings[1].Problem != "blocklisted" {
+		t.Fatalf("unexpected findings: %+v", report.Findings)
+	}
+}
+
+func TestLoadBlocklistInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("not-a-hash\n"), 0o644); err != nil {
+		t.Fatalf("write blocklist: %v", err)
+	}
+	if _, err := LoadBlocklist(path); err == nil {
+		t.Fatal("expected error for invalid hash")
+	}
+}
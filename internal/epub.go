@@ -2,12 +2,17 @@ package internal
 
 import (
 	"archive/zip"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -16,41 +21,19 @@ func generateUUID() string {
 	return uuid.New().String()
 }
 
-func CreateEPUB(folder string, meta *Metadata, output string) error {
-	tempOPF := filepath.Join(folder, "metadata.opf")
-	opf := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<package xmlns="http://www.idpf.org/2007/opf" unique-identifier="BookId" version="2.0">
-  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
-    <dc:title>%s</dc:title>
-    <dc:creator>%s</dc:creator>
-    <dc:description>%s</dc:description>
-    <dc:subject>%s</dc:subject>
-    <dc:source>%s</dc:source>
-    <dc:identifier id="BookId">urn:uuid:%s</dc:identifier>
-  </metadata>
-  <manifest>
-    <item id="cover" href="cover.jpg" media-type="image/jpeg"/>
-    <item id="opf" href="metadata.opf" media-type="application/oebps-package+xml"/>
-  </manifest>
-  <spine>
-  </spine>
-</package>`, meta.Title, meta.Author, meta.Description, meta.Genres, meta.URL, generateUUID())
-
-	err := os.WriteFile(tempOPF, []byte(opf), 0644)
-	if err != nil {
-		log.Printf("❌ Ошибка записи OPF файла: %v", err)
-		return err
-	}
-
-	coverPath := filepath.Join(folder, "cover.jpg")
-	if meta.CoverURL != "" {
-		log.Printf("🖼 Загрузка обложки: %s", meta.CoverURL)
-		err := DownloadFile(meta.CoverURL, coverPath)
-		if err != nil {
-			log.Printf("⚠️ Ошибка загрузки обложки: %v", err)
-		}
-	}
+// epubImage is a page as stored inside the EPUB.
+type epubImage struct {
+	page  *Page
+	id    string
+	href  string // relative to OEBPS/
+	xhtml string // relative to OEBPS/
+	media string
+}
 
+// CreateEPUB writes a fixed-layout EPUB 3 with one XHTML document per page.
+// Detected panels are emitted as Kindle region magnification targets so that
+// tapping a panel zooms it on e-ink devices.
+func CreateEPUB(pages []*Page, meta *Metadata, profile OutputProfile, output string) error {
 	outFile, err := os.Create(output)
 	if err != nil {
 		log.Printf("❌ Не удалось создать EPUB файл: %v", err)
@@ -63,41 +46,308 @@ func CreateEPUB(folder string, meta *Metadata, output string) error {
 
 	log.Printf("📘 Упаковка EPUB: %s", output)
 
-	err = filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+	images := make([]epubImage, 0, len(pages))
+	width, height := 0, 0
+	for i, p := range pages {
+		ext := strings.ToLower(path.Ext(p.Name))
+		media := "image/jpeg"
+		if ext == ".png" {
+			media = "image/png"
+		}
+		images = append(images, epubImage{
+			page:  p,
+			id:    fmt.Sprintf("img%04d", i+1),
+			href:  fmt.Sprintf("images/%04d%s", i+1, ext),
+			xhtml: fmt.Sprintf("pages/%04d.xhtml", i+1),
+			media: media,
+		})
+		width, height = max(width, p.Width), max(height, p.Height)
+	}
+
+	err = writeEPUB(zipWriter, images, meta, profile, width, height)
+	if err != nil {
+		log.Printf("❌ Ошибка упаковки EPUB: %v", err)
+	} else {
+		log.Printf("✅ EPUB создан: %s", output)
+	}
+	return err
+}
+
+func writeEPUB(zw *zip.Writer, images []epubImage, meta *Metadata, profile OutputProfile, width, height int) error {
+	// The mimetype entry must come first and be stored uncompressed.
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	magnify := false
+	for _, img := range images {
+		if len(img.page.Panels) > 0 {
+			magnify = true
+		}
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"META-INF/container.xml", []byte(epubContainer)},
+		{"OEBPS/content.opf", epubPackage(images, meta, profile, width, height, magnify)},
+		{"OEBPS/nav.xhtml", epubNav(images, meta)},
+		{"OEBPS/toc.ncx", epubNCX(images, meta)},
+		{"OEBPS/style.css", []byte(epubStyle)},
+	}
+	for _, img := range images {
+		files = append(files, struct {
+			name string
+			data []byte
+		}{"OEBPS/" + img.xhtml, epubPage(img, meta)})
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
 		if err != nil {
-			log.Printf("⚠️ Ошибка обхода файла: %v", err)
 			return err
 		}
-		if info.IsDir() {
-			return nil
-		}
-		if !isImage(info.Name()) && !strings.HasSuffix(info.Name(), ".opf") && !strings.HasSuffix(info.Name(), ".jpg") {
-			return nil
+		if _, err := w.Write(f.data); err != nil {
+			return err
 		}
+	}
 
-		relPath, _ := filepath.Rel(folder, path)
-		writer, err := zipWriter.Create(relPath)
+	for _, img := range images {
+		w, err := zw.Create("OEBPS/" + img.href)
 		if err != nil {
-			log.Printf("❌ Не удалось добавить файл в EPUB: %v", err)
 			return err
 		}
-
-		file, err := os.Open(path)
+		file, err := os.Open(img.page.Path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, file)
+		file.Close()
 		if err != nil {
-			log.Printf("❌ Не удалось открыть файл: %v", err)
 			return err
 		}
-		defer file.Close()
+	}
+	return nil
+}
 
-		_, err = io.Copy(writer, file)
-		return err
-	})
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
 
-	if err != nil {
-		log.Printf("❌ Ошибка упаковки EPUB: %v", err)
-	} else {
-		log.Printf("✅ EPUB создан: %s", output)
+const epubStyle = `@page { margin: 0; }
+body { margin: 0; padding: 0; }
+.page { position: relative; margin: 0; padding: 0; }
+.page > img { position: absolute; left: 0; top: 0; }
+.panel { position: absolute; }
+.panel a { display: block; width: 100%; height: 100%; }
+.target-mag-parent { position: absolute; left: 0; top: 0; width: 100%; height: 100%; display: none; }
+.target-mag-lb { position: absolute; left: 0; top: 0; width: 100%; height: 100%; background-color: #000; opacity: 0.6; }
+.target-mag { position: absolute; overflow: hidden; }
+.target-mag img { position: absolute; }
+`
+
+// marcRoles are the MARC relator codes of creator roles.
+var marcRoles = map[string]string{RoleWriter: "aut", RoleArtist: "ill"}
+
+func epubPackage(images []epubImage, meta *Metadata, profile OutputProfile, width, height int, magnify bool) []byte {
+	e := html.EscapeString
+	direction := "ltr"
+	writingMode := "horizontal-lr"
+	if profile.ReadingDirection == DirectionRTL {
+		direction = "rtl"
+		writingMode = "horizontal-rl"
 	}
 
-	return err
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="BookId" prefix="rendition: http://www.idpf.org/vocab/rendition/#">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(&b, "    <dc:identifier id=\"BookId\">urn:uuid:%s</dc:identifier>\n", generateUUID())
+	fmt.Fprintf(&b, "    <dc:title id=\"title\">%s</dc:title>\n", e(meta.Title))
+	b.WriteString("    <meta refines=\"#title\" property=\"title-type\">main</meta>\n")
+	series := meta.Series
+	for _, alt := range series.AltTitles {
+		fmt.Fprintf(&b, "    <dc:title>%s</dc:title>\n", e(alt))
+	}
+	if series.Title != "" {
+		fmt.Fprintf(&b, "    <meta property=\"belongs-to-collection\" id=\"series\">%s</meta>\n", e(series.Title))
+		b.WriteString("    <meta refines=\"#series\" property=\"collection-type\">series</meta>\n")
+		if meta.Volume != "" {
+			fmt.Fprintf(&b, "    <meta refines=\"#series\" property=\"group-position\">%s</meta>\n", e(meta.Volume))
+		}
+	}
+	language := meta.Language
+	if language == "" {
+		language = DefaultLanguage
+	}
+	fmt.Fprintf(&b, "    <dc:language>%s</dc:language>\n", e(language))
+	// One dc:creator per person, refined with each of their roles.
+	var creators []string
+	for _, c := range series.Creators {
+		creators = mergeTags(creators, c.Name)
+	}
+	for i, name := range creators {
+		fmt.Fprintf(&b, "    <dc:creator id=\"creator%d\">%s</dc:creator>\n", i+1, e(name))
+		for _, role := range []string{RoleWriter, RoleArtist} {
+			if slices.Contains(series.People(role), name) {
+				fmt.Fprintf(&b, "    <meta refines=\"#creator%d\" property=\"role\" scheme=\"marc:relators\">%s</meta>\n", i+1, marcRoles[role])
+			}
+		}
+	}
+	for _, publisher := range series.Publishers {
+		fmt.Fprintf(&b, "    <dc:publisher>%s</dc:publisher>\n", e(publisher))
+	}
+	if meta.Group != "" {
+		fmt.Fprintf(&b, "    <dc:contributor id=\"group\">%s</dc:contributor>\n", e(meta.Group))
+		b.WriteString("    <meta refines=\"#group\" property=\"role\" scheme=\"marc:relators\">trl</meta>\n")
+	}
+	if series.Description != "" {
+		fmt.Fprintf(&b, "    <dc:description>%s</dc:description>\n", e(series.Description))
+	}
+	for _, subject := range append(slices.Clone(series.Genres), series.Tags...) {
+		fmt.Fprintf(&b, "    <dc:subject>%s</dc:subject>\n", e(subject))
+	}
+	if series.Year > 0 {
+		fmt.Fprintf(&b, "    <dc:date>%d</dc:date>\n", series.Year)
+	}
+	if series.URL != "" {
+		fmt.Fprintf(&b, "    <dc:source>%s</dc:source>\n", e(series.URL))
+	}
+	fmt.Fprintf(&b, "    <meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString(`    <meta property="rendition:layout">pre-paginated</meta>
+    <meta property="rendition:spread">landscape</meta>
+    <meta property="rendition:orientation">portrait</meta>
+    <meta name="book-type" content="comic"/>
+    <meta name="fixed-layout" content="true"/>
+    <meta name="zero-gutter" content="true"/>
+    <meta name="zero-margin" content="true"/>
+`)
+	fmt.Fprintf(&b, "    <meta name=\"original-resolution\" content=\"%dx%d\"/>\n", width, height)
+	fmt.Fprintf(&b, "    <meta name=\"primary-writing-mode\" content=\"%s\"/>\n", writingMode)
+	fmt.Fprintf(&b, "    <meta name=\"RegionMagnification\" content=\"%t\"/>\n", magnify)
+	if len(images) > 0 {
+		fmt.Fprintf(&b, "    <meta name=\"cover\" content=\"%s\"/>\n", images[0].id)
+	}
+	b.WriteString("  </metadata>\n  <manifest>\n")
+	b.WriteString(`    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+`)
+	for i, img := range images {
+		props := ""
+		if i == 0 {
+			props = ` properties="cover-image"`
+		}
+		fmt.Fprintf(&b, "    <item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", img.id, img.href, img.media, props)
+		fmt.Fprintf(&b, "    <item id=\"page%04d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, img.xhtml)
+	}
+	fmt.Fprintf(&b, "  </manifest>\n  <spine toc=\"ncx\" page-progression-direction=\"%s\">\n", direction)
+	for i := range images {
+		fmt.Fprintf(&b, "    <itemref idref=\"page%04d\"/>\n", i+1)
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return b.Bytes()
+}
+
+func epubNav(images []epubImage, meta *Metadata) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>%s</title></head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <ol>
+`, html.EscapeString(meta.Title))
+	for _, mark := range epubBookmarks(images, meta) {
+		fmt.Fprintf(&b, "      <li><a href=\"%s\">%s</a></li>\n", mark.href, html.EscapeString(mark.label))
+	}
+	b.WriteString("    </ol>\n  </nav>\n</body>\n</html>\n")
+	return b.Bytes()
+}
+
+func epubNCX(images []epubImage, meta *Metadata) []byte {
+	var b bytes.Buffer
+	title := html.EscapeString(meta.Title)
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head/>
+  <docTitle><text>%s</text></docTitle>
+  <navMap>
+`, title)
+	for i, mark := range epubBookmarks(images, meta) {
+		fmt.Fprintf(&b, "    <navPoint id=\"nav%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n",
+			i+1, i+1, html.EscapeString(mark.label), mark.href)
+	}
+	b.WriteString("  </navMap>\n</ncx>\n")
+	return b.Bytes()
+}
+
+type epubBookmark struct {
+	label string
+	href  string
+}
+
+// epubBookmarks lists the table of contents: pages with a bookmark, or just
+// the first page when there are none.
+func epubBookmarks(images []epubImage, meta *Metadata) []epubBookmark {
+	var marks []epubBookmark
+	for _, img := range images {
+		if img.page.Bookmark != "" {
+			marks = append(marks, epubBookmark{label: img.page.Bookmark, href: img.xhtml})
+		}
+	}
+	if len(marks) == 0 && len(images) > 0 {
+		marks = append(marks, epubBookmark{label: meta.Title, href: images[0].xhtml})
+	}
+	return marks
+}
+
+// epubPage renders the XHTML document of one page. The viewport matches the
+// image so panel coordinates can be given in pixels.
+func epubPage(img epubImage, meta *Metadata) []byte {
+	p := img.page
+	src := "../" + img.href
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+  <title>%s</title>
+  <meta name="viewport" content="width=%d, height=%d"/>
+  <link href="../style.css" type="text/css" rel="stylesheet"/>
+</head>
+<body>
+  <div class="page" style="width:%dpx;height:%dpx;">
+    <img src="%s" width="%d" height="%d" alt=""/>
+`, html.EscapeString(meta.Title), p.Width, p.Height, p.Width, p.Height, src, p.Width, p.Height)
+
+	for i, r := range p.Panels {
+		id := fmt.Sprintf("panel-%d", i+1)
+		target := id + "-target"
+		trigger, _ := json.Marshal(map[string]any{"targetId": target, "ordinal": i + 1})
+		fmt.Fprintf(&b, "    <div id=\"%s\" class=\"panel\" style=\"left:%dpx;top:%dpx;width:%dpx;height:%dpx;\">\n", id, r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+		fmt.Fprintf(&b, "      <a class=\"app-amzn-magnify\" data-app-amzn-magnify='%s'></a>\n    </div>\n", trigger)
+
+		// The magnified view scales the panel to fill the page and centres it.
+		scale := min(float64(p.Width)/float64(r.Dx()), float64(p.Height)/float64(r.Dy()))
+		vw, vh := float64(r.Dx())*scale, float64(r.Dy())*scale
+		fmt.Fprintf(&b, "    <div id=\"%s\" class=\"target-mag-parent\">\n      <div class=\"target-mag-lb\"></div>\n", target)
+		fmt.Fprintf(&b, "      <div class=\"target-mag\" style=\"left:%.0fpx;top:%.0fpx;width:%.0fpx;height:%.0fpx;\">\n",
+			(float64(p.Width)-vw)/2, (float64(p.Height)-vh)/2, vw, vh)
+		fmt.Fprintf(&b, "        <img src=\"%s\" style=\"left:%.0fpx;top:%.0fpx;width:%.0fpx;height:%.0fpx;\" alt=\"\"/>\n      </div>\n    </div>\n",
+			src, -float64(r.Min.X)*scale, -float64(r.Min.Y)*scale, float64(p.Width)*scale, float64(p.Height)*scale)
+	}
+
+	b.WriteString("  </div>\n</body>\n</html>\n")
+	return b.Bytes()
 }
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readZipEntries(t *testing.T, path string) ([]*zip.File, map[string]string) {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	t.Cleanup(func() { r.Close() })
+	contents := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open entry %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read entry %s: %v", f.Name, err)
+		}
+		contents[f.Name] = string(data)
+	}
+	return r.File, contents
+}
+
+func TestCreateEPUB(t *testing.T) {
+	dir := t.TempDir()
+	img, _ := threePanelPage()
+	pagePath := filepath.Join(dir, "001.png")
+	writeImageFile(t, pagePath, img)
+	second := filepath.Join(dir, "002.jpg")
+	writeJPEG(t, second, 200, 300)
+
+	pages := []*Page{
+		{Path: pagePath, Name: "001.png", Width: 200, Height: 300, Panels: DetectPanels(img, DirectionRTL)},
+		{Path: second, Name: "002.jpg", Width: 200, Height: 300},
+	}
+	out := filepath.Join(dir, "book.epub")
+	meta := &Metadata{Title: "Том <1> & Co", Volume: "1", Series: Series{
+		Title:      "Том",
+		Creators:   []Creator{{"Writer", RoleWriter}, {"Painter", RoleArtist}, {"Writer", RoleArtist}},
+		Publishers: []string{"Shueisha"},
+		Genres:     []string{"Action", "Drama"},
+	}}
+	if err := CreateEPUB(pages, meta, DefaultProfile(), out); err != nil {
+		t.Fatalf("CreateEPUB error: %v", err)
+	}
+
+	files, contents := readZipEntries(t, out)
+	if files[0].Name != "mimetype" || files[0].Method != zip.Store {
+		t.Fatalf("first entry must be stored mimetype, got %s (method %d)", files[0].Name, files[0].Method)
+	}
+
+	opf := contents["OEBPS/content.opf"]
+	for _, want := range []string{
+		`page-progression-direction="rtl"`,
+		`<dc:title id="title">Том &lt;1&gt; &amp; Co</dc:title>`,
+		`<dc:creator id="creator1">Writer</dc:creator>`,
+		`<meta refines="#creator1" property="role" scheme="marc:relators">aut</meta>`,
+		`<meta refines="#creator1" property="role" scheme="marc:relators">ill</meta>`,
+		`<dc:creator id="creator2">Painter</dc:creator>`,
+		`<meta refines="#creator2" property="role" scheme="marc:relators">ill</meta>`,
+		`<dc:publisher>Shueisha</dc:publisher>`,
+		`<dc:subject>Action</dc:subject>`,
+		`<dc:subject>Drama</dc:subject>`,
+		`<meta refines="#series" property="group-position">1</meta>`,
+		`href="images/0001.png" media-type="image/png" properties="cover-image"`,
+		`<meta name="RegionMagnification" content="true"/>`,
+		`<itemref idref="page0002"/>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Fatalf("content.opf missing %q:\n%s", want, opf)
+		}
+	}
+
+	page := contents["OEBPS/pages/0001.xhtml"]
+	if strings.Count(page, `class="app-amzn-magnify"`) != 3 {
+		t.Fatalf("page 1 should have 3 magnification regions:\n%s", page)
+	}
+	if !strings.Contains(page, `{"ordinal":1,"targetId":"panel-1-target"}`) {
+		t.Fatalf("page 1 missing first region trigger:\n%s", page)
+	}
+	if strings.Contains(contents["OEBPS/pages/0002.xhtml"], "app-amzn-magnify") {
+		t.Fatal("page without panels should not have regions")
+	}
+	if _, ok := contents["OEBPS/images/0002.jpg"]; !ok {
+		t.Fatal("image 0002.jpg not packed")
+	}
+}
+
+func TestWritePanelSidecar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.panels.json")
+	pages := []*Page{{Name: "001.jpg", Width: 10, Height: 20, Panels: []image.Rectangle{image.Rect(1, 2, 5, 9)}}}
+	if err := writePanelSidecar(pages, path); err != nil {
+		t.Fatalf("writePanelSidecar error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read sidecar: %v", err)
+	}
+	var sidecar panelSidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		t.Fatalf("decode sidecar: %v", err)
+	}
+	if len(sidecar.Pages) != 1 || sidecar.Pages[0].Panels[0] != [4]int{1, 2, 4, 7} {
+		t.Fatalf("unexpected sidecar: %s", data)
+	}
+}
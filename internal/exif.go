@@ -0,0 +1,237 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// jpegInfo is what the normaliser needs to know about a JPEG before decoding
+// it: how it should be rotated, whether it is CMYK and which metadata it
+// carries.
+type jpegInfo struct {
+	Orientation int
+	HasGPS      bool
+	Components  int
+	// ICC holds the APP2 ICC_PROFILE segments as found in the file.
+	ICC         []byte
+	SRGBProfile bool
+	// Metadata is true when the file has segments that stripJPEGMetadata
+	// would remove.
+	Metadata bool
+}
+
+var errNotJPEG = errors.New("не JPEG")
+
+// jpegStripped lists the APPn segments removed from pages: EXIF/XMP (APP1),
+// Photoshop IRB/IPTC (APP13) and comments.
+func jpegStripped(marker byte) bool {
+	return marker == 0xE1 || marker == 0xED || marker == 0xFE
+}
+
+// scanJPEG walks the segments before the first scan. For every segment it
+// calls fn with the marker and payload (without the length field).
+func scanJPEG(data []byte, fn func(marker byte, start, end int)) error {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return errNotJPEG
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return errors.New("повреждённая структура JPEG")
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// fill byte
+			i++
+			continue
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return errors.New("повреждённый сегмент JPEG")
+		}
+		fn(marker, i, i+2+length)
+		if marker == 0xDA {
+			return nil
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+func readJPEGInfo(data []byte) (jpegInfo, error) {
+	info := jpegInfo{Orientation: 1}
+	err := scanJPEG(data, func(marker byte, start, end int) {
+		payload := data[start+4 : end]
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")):
+			info.Orientation, info.HasGPS = parseExif(payload[6:])
+		case marker == 0xE2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")):
+			info.ICC = append(info.ICC, data[start:end]...)
+			if bytes.Contains(payload, []byte("sRGB")) {
+				info.SRGBProfile = true
+			}
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC:
+			// SOFn: precision(1) height(2) width(2) components(1)
+			if len(payload) >= 6 {
+				info.Components = int(payload[5])
+			}
+		}
+		if jpegStripped(marker) {
+			info.Metadata = true
+		}
+	})
+	return info, err
+}
+
+// stripJPEGMetadata removes EXIF, XMP, IPTC and comments without touching the
+// compressed image data.
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	last := 2
+	err := scanJPEG(data, func(marker byte, start, end int) {
+		if jpegStripped(marker) {
+			out = append(out, data[last:start]...)
+			last = end
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[last:]...), nil
+}
+
+// insertJPEGSegments puts whole segments right after the SOI marker.
+func insertJPEGSegments(data, segments []byte) []byte {
+	out := make([]byte, 0, len(data)+len(segments))
+	out = append(out, data[:2]...)
+	out = append(out, segments...)
+	return append(out, data[2:]...)
+}
+
+// parseExif reads the orientation tag and the presence of a GPS block from a
+// TIFF-structured EXIF payload.
+func parseExif(tiff []byte) (orientation int, hasGPS bool) {
+	orientation = 1
+	if len(tiff) < 8 {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return
+		}
+		switch order.Uint16(tiff[entry:]) {
+		case 0x0112:
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				orientation = o
+			}
+		case 0x8825:
+			hasGPS = true
+		}
+	}
+	return
+}
+
+// pngStripped lists the ancillary PNG chunks removed from pages.
+var pngStripped = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// scanPNG calls fn for every chunk with its type and the byte range of the
+// whole chunk (length, type, data and CRC).
+func scanPNG(data []byte, fn func(kind string, start, end int)) error {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return errors.New("не PNG")
+	}
+	i := len(signature)
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return errors.New("повреждённый блок PNG")
+		}
+		fn(string(data[i+4:i+8]), i, end)
+		i = end
+	}
+	return nil
+}
+
+// readPNGExif returns the orientation from an eXIf chunk and whether the file
+// carries metadata chunks.
+func readPNGExif(data []byte) (orientation int, hasGPS bool, metadata bool, err error) {
+	orientation = 1
+	err = scanPNG(data, func(kind string, start, end int) {
+		if kind == "eXIf" {
+			chunk := data[start+8 : end-4]
+			if crc32.ChecksumIEEE(data[start+4:end-4]) == binary.BigEndian.Uint32(data[end-4:]) {
+				orientation, hasGPS = parseExif(chunk)
+			}
+		}
+		if pngStripped[kind] {
+			metadata = true
+		}
+	})
+	return
+}
+
+// readPNGICC returns the iCCP chunk, if any, and whether its profile name
+// says sRGB.
+func readPNGICC(data []byte) (chunk []byte, srgb bool, err error) {
+	err = scanPNG(data, func(kind string, start, end int) {
+		if kind == "iCCP" {
+			chunk = data[start:end]
+			name, _, _ := bytes.Cut(data[start+8:end-4], []byte{0})
+			srgb = bytes.Contains(name, []byte("sRGB"))
+		}
+	})
+	return
+}
+
+// insertPNGChunks puts whole chunks right after IHDR, where iCCP belongs.
+func insertPNGChunks(data, chunks []byte) []byte {
+	ihdrEnd := 8
+	scanPNG(data, func(kind string, start, end int) {
+		if kind == "IHDR" {
+			ihdrEnd = end
+		}
+	})
+	out := make([]byte, 0, len(data)+len(chunks))
+	out = append(out, data[:ihdrEnd]...)
+	out = append(out, chunks...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	last := 0
+	err := scanPNG(data, func(kind string, start, end int) {
+		if pngStripped[kind] {
+			out = append(out, data[last:start]...)
+			last = end
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[last:]...), nil
+}
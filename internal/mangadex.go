@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const mangaDexAPI = "https://api.mangadex.org"
+
+type mangaDexSearch struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// mangaDexAggregate is the /manga/{id}/aggregate response. MangaDex encodes
+// empty objects as [], so volumes and chapters are decoded lazily.
+type mangaDexAggregate struct {
+	Volumes json.RawMessage `json:"volumes"`
+}
+
+type mangaDexVolume struct {
+	Volume   string          `json:"volume"`
+	Chapters json.RawMessage `json:"chapters"`
+}
+
+type mangaDexChapter struct {
+	Chapter string `json:"chapter"`
+}
+
+// FetchMangaDexVolumes returns the chapter numbers of every numbered volume
+// of the series as known to MangaDex.
+func FetchMangaDexVolumes(name string) (map[string][]string, error) {
+	id, err := mangaDexID(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var aggregate mangaDexAggregate
+	if err := mangaDexGet("/manga/"+id+"/aggregate", &aggregate); err != nil {
+		return nil, err
+	}
+
+	volumes := map[string]mangaDexVolume{}
+	if err := decodeMangaDexObject(aggregate.Volumes, &volumes); err != nil {
+		return nil, err
+	}
+	result := map[string][]string{}
+	for _, v := range volumes {
+		// Chapters without a volume are listed under "none".
+		if v.Volume == "" || v.Volume == "none" {
+			continue
+		}
+		chapters := map[string]mangaDexChapter{}
+		if err := decodeMangaDexObject(v.Chapters, &chapters); err != nil {
+			return nil, err
+		}
+		for _, c := range chapters {
+			if c.Chapter != "" && c.Chapter != "none" {
+				result[trimNumber(v.Volume)] = append(result[trimNumber(v.Volume)], trimNumber(c.Chapter))
+			}
+		}
+	}
+	return result, nil
+}
+
+// mangaDexID returns the pinned MangaDex ID of the series or searches for it.
+func mangaDexID(name string) (string, error) {
+	if pin := findPin(name); pin != nil && pin.MangaDex != "" {
+		log.Printf("📌 %s привязана к MangaDex %s", name, pin.MangaDex)
+		return pin.MangaDex, nil
+	}
+
+	query := strings.ReplaceAll(name, "_", " ")
+	log.Printf("🔎 Запрос MangaDex по имени: %s", query)
+
+	var search mangaDexSearch
+	if err := mangaDexGet("/manga?limit=1&title="+url.QueryEscape(query), &search); err != nil {
+		return "", err
+	}
+	if len(search.Data) == 0 {
+		return "", errors.New("манга не найдена")
+	}
+	return search.Data[0].ID, nil
+}
+
+func mangaDexGet(path string, out any) error {
+	req, err := http.NewRequest("GET", mangaDexAPI+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "manga-converter")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("статус ответа %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func decodeMangaDexObject(data json.RawMessage, out any) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] == '[' {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFetchMangaDexVolumes(t *testing.T) {
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body string
+		switch req.URL.Path {
+		case "/manga":
+			if req.URL.Query().Get("title") != "Test Manga" {
+				t.Fatalf("unexpected title: %s", req.URL.Query().Get("title"))
+			}
+			body = `{"data": [{"id": "abc"}]}`
+		case "/manga/abc/aggregate":
+			body = `{"result": "ok", "volumes": {
+				"1": {"volume": "1", "chapters": {"1": {"chapter": "1"}, "2": {"chapter": "2"}}},
+				"2": {"volume": "2", "chapters": []},
+				"none": {"volume": "none", "chapters": {"9": {"chapter": "9"}}}
+			}}`
+		default:
+			t.Fatalf("unexpected path: %s", req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	}))
+
+	got, err := FetchMangaDexVolumes("Test_Manga")
+	if err != nil {
+		t.Fatalf("FetchMangaDexVolumes error: %v", err)
+	}
+	slices.Sort(got["1"])
+	if want := map[string][]string{"1": {"1", "2"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FetchMangaDexVolumes = %v, want %v", got, want)
+	}
+}
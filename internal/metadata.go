@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"strings"
+)
+
+// Metadata describes one book: the series it belongs to and where the book
+// sits in it.
+type Metadata struct {
+	// Title is the book title: the series title for the series itself,
+	// "Title — Том 1" for a volume, "Title — Том 1, часть 2" for a part.
+	Title  string
+	Series Series
+	// Volume and Number identify the book in the series; Number is the
+	// chapter or chapter range.
+	Volume string
+	Number string
+	// Language is the BCP 47 language of the text.
+	Language string
+	// Group and ReleaseTags come from the release name, not from the
+	// provider.
+	Group       string
+	ReleaseTags []string
+	// CoverFile is the downloaded series cover, empty when there is none.
+	CoverFile string
+}
+
+// Series is what a provider knows about the series as a whole.
+type Series struct {
+	// Title is the preferred name; Titles are the provider titles it was
+	// chosen from and AltTitles the ones not chosen.
+	Title     string
+	Titles    Titles
+	AltTitles []string
+
+	Creators    []Creator
+	Publishers  []string
+	Description string
+	Genres      []string
+	// Tags are provider themes and demographics such as "Школа" or "Сёнэн".
+	Tags []string
+	Year int
+	// Status is one of the Status constants, empty when unknown.
+	Status string
+	// AgeRating uses the ComicInfo vocabulary, empty when unknown.
+	AgeRating string
+	// VolumeCount and ChapterCount are the series totals, 0 while ongoing.
+	VolumeCount  int
+	ChapterCount int
+
+	URL string
+	IDs ProviderIDs
+	// Covers are the cover variants, largest first.
+	Covers []Cover
+}
+
+// Creator is a person credited for the series.
+type Creator struct {
+	Name string
+	Role string // RoleWriter or RoleArtist
+}
+
+// ProviderIDs identify the series at each provider; zero when unknown.
+type ProviderIDs struct {
+	Shikimori   int
+	AniList     int
+	MyAnimeList int
+	MangaDex    string
+}
+
+// Cover is one variant of the series cover.
+type Cover struct {
+	URL   string
+	Width int // 0 when unknown
+}
+
+const (
+	RoleWriter = "writer"
+	RoleArtist = "artist"
+)
+
+const (
+	StatusOngoing   = "ongoing"
+	StatusCompleted = "completed"
+	StatusHiatus    = "hiatus"
+	StatusCancelled = "cancelled"
+	StatusAnnounced = "announced"
+)
+
+const (
+	AgeRatingEveryone = "Everyone"
+	AgeRatingAdult    = "Adults Only 18+"
+)
+
+// DefaultLanguage is the language of the books unless a release says
+// otherwise.
+const DefaultLanguage = "ru"
+
+// newMetadata wraps provider series data into the metadata of the series
+// itself, choosing the title; folder is the fallback title.
+func newMetadata(series Series, folder string) *Metadata {
+	series.chooseTitle(folder)
+	return &Metadata{Title: series.Title, Series: series, Language: DefaultLanguage}
+}
+
+// People returns the names credited with a role, in provider order.
+func (s Series) People(role string) []string {
+	var names []string
+	for _, c := range s.Creators {
+		if c.Role == role {
+			names = mergeTags(names, c.Name)
+		}
+	}
+	return names
+}
+
+// addCreator credits a person with the roles found in a provider role such
+// as "Story & Art" or "Original Creator"; unknown roles are ignored.
+func (s *Series) addCreator(name, role string) {
+	if name == "" {
+		return
+	}
+	if strings.Contains(role, "Story") || role == "Original Creator" {
+		s.Creators = append(s.Creators, Creator{Name: name, Role: RoleWriter})
+	}
+	if strings.Contains(role, "Art") {
+		s.Creators = append(s.Creators, Creator{Name: name, Role: RoleArtist})
+	}
+}
+
+// Cover returns the URL of the largest cover variant.
+func (s Series) Cover() string {
+	if len(s.Covers) == 0 {
+		return ""
+	}
+	return s.Covers[0].URL
+}
+
+// adultGenres mark a series as adults only when no rating is given.
+var adultGenres = []string{"Hentai", "Erotica"}
+
+func ratingByGenres(genres []string) string {
+	for _, g := range genres {
+		for _, adult := range adultGenres {
+			if strings.EqualFold(g, adult) {
+				return AgeRatingAdult
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"cmp"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NameInfo is what ParseName finds in a folder, file or archive name.
+type NameInfo struct {
+	Volume     string // "1", "" if absent
+	Chapter    string // first chapter, "3" or "3.5"
+	ChapterEnd string // last chapter of a range such as "Ch. 1-5"
+	// Special is an extra/omake/bonus marker as written in the name.
+	Special string
+	// Tags are the bracketed parts in order of appearance: groups, "Digital",
+	// resolutions, years.
+	Tags []string
+	// Title is what is left after removing tags and markers.
+	Title string
+}
+
+var (
+	nameTag      = regexp.MustCompile(`[\[({【]([^\])}】]*)[\])}】]`)
+	nameVolume   = regexp.MustCompile(`(?i)(?:^|[^\pL])(?:volume|vol|tome|тома|том|т|v)[ ._-]*(\d+(?:\.\d+)?)`)
+	nameVolumeJA = regexp.MustCompile(`第?\s*(\d+)\s*巻`)
+	nameChapter  = regexp.MustCompile(`(?i)(?:^|[^\pL])(?:chapter|chap|ch|c|episode|ep|главы|глава|гл|#)[ ._-]*(\d+(?:\.\d+)?)(?:[ ]*[-–~][ ]*(\d+(?:\.\d+)?))?`)
+	nameChapJA   = regexp.MustCompile(`第?\s*(\d+(?:\.\d+)?)\s*[話章回]`)
+	nameSpecial  = regexp.MustCompile(`(?i)(?:^|[^\pL])(extras?|omake|specials?|sp|bonus|side[ _-]?story|one[ _-]?shot|экстра|спецвыпуск|спешл|бонус|омаке)(?:$|[^\pL])|(番外編|おまけ|特別編)`)
+	kanjiNumeral = regexp.MustCompile(`第([〇一二三四五六七八九十百]+)([巻話章回])`)
+	nameSpaces   = regexp.MustCompile(`[\s_.]+`)
+)
+
+// ParseName extracts volume, chapter range, special marker and release tags
+// from Russian ("Том 01", "Глава 3"), English ("Vol.1 Ch.3", "v01", "c003")
+// and Japanese ("第1巻", "第三話") names.
+func ParseName(name string) NameInfo {
+	var info NameInfo
+	s := normalizeWidth(name)
+	if isImage(s) {
+		s = strings.TrimSuffix(s, path.Ext(s))
+	}
+	s = kanjiNumeral.ReplaceAllStringFunc(s, func(m string) string {
+		sub := kanjiNumeral.FindStringSubmatch(m)
+		return "第" + strconv.Itoa(kanjiNumber(sub[1])) + sub[2]
+	})
+
+	for _, m := range nameTag.FindAllStringSubmatch(s, -1) {
+		if tag := strings.TrimSpace(m[1]); tag != "" {
+			info.Tags = append(info.Tags, tag)
+		}
+	}
+	s = nameTag.ReplaceAllString(s, " ")
+
+	if m := nameVolume.FindStringSubmatch(s); m != nil {
+		info.Volume = trimNumber(m[1])
+	} else if m := nameVolumeJA.FindStringSubmatch(s); m != nil {
+		info.Volume = trimNumber(m[1])
+	}
+
+	if m := nameChapter.FindStringSubmatch(s); m != nil {
+		info.Chapter = trimNumber(m[1])
+		if end := trimNumber(m[2]); end != "" && compareNumbers(end, info.Chapter) > 0 {
+			info.ChapterEnd = end
+		}
+	} else if m := nameChapJA.FindStringSubmatch(s); m != nil {
+		info.Chapter = trimNumber(m[1])
+	}
+
+	if m := nameSpecial.FindStringSubmatch(s); m != nil {
+		info.Special = m[1] + m[2]
+	}
+
+	for _, re := range []*regexp.Regexp{nameVolume, nameVolumeJA, nameChapter, nameChapJA, nameSpecial} {
+		s = re.ReplaceAllString(s, " ")
+	}
+	info.Title = strings.Trim(nameSpaces.ReplaceAllString(s, " "), " -–—,")
+	return info
+}
+
+// ChapterRange is "3" for a single chapter and "1-5" for a range.
+func (n NameInfo) ChapterRange() string {
+	if n.ChapterEnd != "" {
+		return n.Chapter + "-" + n.ChapterEnd
+	}
+	return n.Chapter
+}
+
+// parseVolumeName parses a volume folder name. A bare number ("01",
+// "01 (Digital)") is taken as the volume number.
+func parseVolumeName(name string) NameInfo {
+	info := ParseName(name)
+	if info.Volume == "" && info.Chapter == "" && firstNumber.FindString(info.Title) == info.Title {
+		info.Volume = trimNumber(info.Title)
+	}
+	return info
+}
+
+// volumeTitle describes a volume for book titles: "Том 1", "Том 1, Extra",
+// "Extra" or the folder name when nothing could be parsed.
+func volumeTitle(name string) string {
+	info := parseVolumeName(name)
+	switch {
+	case info.Volume != "" && info.Special != "":
+		return "Том " + info.Volume + ", " + info.Special
+	case info.Volume != "":
+		return "Том " + info.Volume
+	case info.Special != "":
+		return info.Special
+	}
+	return name
+}
+
+// volumeFileLabel is the volume part of output file names: "Vol_01",
+// "Vol_01_Extra", "Extra" or the folder name.
+func volumeFileLabel(name string) string {
+	info := parseVolumeName(name)
+	switch {
+	case info.Volume != "" && info.Special != "":
+		return "Vol_" + padNumber(info.Volume, 2) + "_" + info.Special
+	case info.Volume != "":
+		return "Vol_" + padNumber(info.Volume, 2)
+	case info.Special != "":
+		return info.Special
+	}
+	return name
+}
+
+// padNumber zero-pads the integer part: padNumber("5.5", 3) is "005.5".
+func padNumber(number string, width int) string {
+	whole, frac, hasFrac := strings.Cut(number, ".")
+	if firstNumber.FindString(whole) != whole {
+		return number
+	}
+	whole = strings.Repeat("0", max(0, width-len(whole))) + whole
+	if hasFrac {
+		return whole + "." + frac
+	}
+	return whole
+}
+
+// compareVolumeNames orders volume folders by volume number, regular volumes
+// before specials, and unnumbered folders last in natural order.
+func compareVolumeNames(a, b string) int {
+	x, y := parseVolumeName(a), parseVolumeName(b)
+	if (x.Volume == "") != (y.Volume == "") {
+		if x.Volume == "" {
+			return 1
+		}
+		return -1
+	}
+	if c := compareNumbers(x.Volume, y.Volume); x.Volume != "" && c != 0 {
+		return c
+	}
+	if (x.Special == "") != (y.Special == "") {
+		if x.Special == "" {
+			return -1
+		}
+		return 1
+	}
+	if c := compareNumbers(x.Chapter, y.Chapter); x.Chapter != "" && y.Chapter != "" && c != 0 {
+		return c
+	}
+	return compareNatural(a, b)
+}
+
+// compareNatural compares strings with digit runs ordered by value, so
+// "page2" sorts before "page10".
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if c := cmp.Compare(len(na), len(nb)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// normalizeWidth composes NFD names from macOS archives and maps full-width
+// digits and brackets common in Japanese names to ASCII.
+func normalizeWidth(s string) string {
+	s = norm.NFC.String(s)
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '０' && r <= '９':
+			return '0' + r - '０'
+		case r == '（':
+			return '('
+		case r == '）':
+			return ')'
+		case r == '［':
+			return '['
+		case r == '］':
+			return ']'
+		}
+		return r
+	}, s)
+}
+
+// kanjiNumber converts numerals such as "十二" or "二〇" to an integer.
+func kanjiNumber(s string) int {
+	digits := map[rune]int{'〇': 0, '一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+	positional := !strings.ContainsAny(s, "十百")
+	total, cur := 0, 0
+	for _, r := range s {
+		switch r {
+		case '百', '十':
+			unit := 10
+			if r == '百' {
+				unit = 100
+			}
+			total += max(cur, 1) * unit
+			cur = 0
+		default:
+			if positional {
+				cur = cur*10 + digits[r]
+			} else {
+				cur = digits[r]
+			}
+		}
+	}
+	return total + cur
+}
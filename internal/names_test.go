@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	cases := []struct {
+		name string
+		want NameInfo
+	}{
+		{"Том 01", NameInfo{Volume: "1"}},
+		{"Vol.1 Ch.3", NameInfo{Volume: "1", Chapter: "3"}},
+		{"v01 (Digital)", NameInfo{Volume: "1", Tags: []string{"Digital"}}},
+		{"第1巻", NameInfo{Volume: "1"}},
+		{"第１２巻", NameInfo{Volume: "12"}},
+		{"第十二話", NameInfo{Chapter: "12"}},
+		{"Глава 5.5", NameInfo{Chapter: "5.5"}},
+		{"Ch. 1-5", NameInfo{Chapter: "1", ChapterEnd: "5"}},
+		{"Т.3 Экстра", NameInfo{Volume: "3", Special: "Экстра"}},
+		{"[Group] Title v03 (Digital) [1080p]", NameInfo{Volume: "3", Tags: []string{"Group", "Digital", "1080p"}, Title: "Title"}},
+		{"c012_p03.png", NameInfo{Chapter: "12", Title: "p03"}},
+		{"Omake", NameInfo{Special: "Omake"}},
+	}
+	for _, tc := range cases {
+		if got := ParseName(tc.name); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("ParseName(%q) = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestVolumeLabels(t *testing.T) {
+	cases := []struct{ name, title, file string }{
+		{"Том 01", "Том 1", "Vol_01"},
+		{"01 (Digital)", "Том 1", "Vol_01"},
+		{"Vol.2 Extra", "Том 2, Extra", "Vol_02_Extra"},
+		{"Bonus", "Bonus", "Bonus"},
+		{"Misc", "Misc", "Misc"},
+	}
+	for _, tc := range cases {
+		if got := volumeTitle(tc.name); got != tc.title {
+			t.Fatalf("volumeTitle(%q) = %q, want %q", tc.name, got, tc.title)
+		}
+		if got := volumeFileLabel(tc.name); got != tc.file {
+			t.Fatalf("volumeFileLabel(%q) = %q, want %q", tc.name, got, tc.file)
+		}
+	}
+}
+
+func TestCompareVolumeNames(t *testing.T) {
+	volumes := []string{"Misc", "Том 10", "Vol.2 Extra", "Том 2", "v01 (Digital)"}
+	slices.SortFunc(volumes, compareVolumeNames)
+	want := []string{"v01 (Digital)", "Том 2", "Vol.2 Extra", "Том 10", "Misc"}
+	if !reflect.DeepEqual(volumes, want) {
+		t.Fatalf("sorted = %v, want %v", volumes, want)
+	}
+
+	names := []string{"page10.jpg", "page2.jpg", "page02b.jpg", "page1.jpg"}
+	slices.SortFunc(names, compareNatural)
+	if want := []string{"page1.jpg", "page2.jpg", "page02b.jpg", "page10.jpg"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("natural order = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"os"
+)
+
+type NormalizeSettings struct {
+	// Enabled applies EXIF orientation, converts CMYK/YCCK JPEGs to RGB and
+	// strips EXIF/GPS, XMP and text metadata from every page.
+	Enabled bool `json:"enabled"`
+}
+
+// NormalizePage rewrites a page so that it is upright, in an RGB or grey
+// colour model and free of uploader metadata. Pages that only carry metadata
+// are stripped losslessly; rotated or CMYK pages are re-encoded.
+func NormalizePage(p *Page, report *VolumeReport) error {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return err
+	}
+
+	orientation, hasGPS, metadata, cmyk := 1, false, false, false
+	// profile is the embedded ICC profile as raw segments or chunk; embed
+	// puts it back into a re-encoded file.
+	var profile []byte
+	var srgb bool
+	var strip func([]byte) ([]byte, error)
+	var embed func(data, profile []byte) []byte
+	switch sniffFormat(data) {
+	case "jpeg":
+		info, err := readJPEGInfo(data)
+		if err != nil {
+			return err
+		}
+		orientation, hasGPS, metadata = info.Orientation, info.HasGPS, info.Metadata
+		cmyk = info.Components == 4
+		profile, srgb = info.ICC, info.SRGBProfile
+		strip, embed = stripJPEGMetadata, insertJPEGSegments
+	case "png":
+		orientation, hasGPS, metadata, err = readPNGExif(data)
+		if err != nil {
+			return err
+		}
+		if profile, srgb, err = readPNGICC(data); err != nil {
+			return err
+		}
+		strip, embed = stripPNGMetadata, insertPNGChunks
+	default:
+		return nil
+	}
+
+	if hasGPS {
+		log.Printf("📍 Страница %s содержит GPS-координаты, они будут удалены", p.Name)
+	}
+
+	if orientation == 1 && !cmyk {
+		if !metadata {
+			return nil
+		}
+		clean, err := strip(data)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(p.Path, clean, 0644)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	upright := normalizeImage(img, orientation)
+	if err := encodeImage(p.Path, upright); err != nil {
+		return err
+	}
+	if orientation >= 5 {
+		p.Width, p.Height = p.Height, p.Width
+		p.OriginalWidth, p.OriginalHeight = p.OriginalHeight, p.OriginalWidth
+	}
+	if orientation != 1 {
+		report.AddFinding(Finding{Page: p.Name, Problem: "exif-orientation", Detail: fmt.Sprintf("ориентация %d", orientation), Action: "normalized"})
+		log.Printf("🔄 Страница %s повёрнута по EXIF (ориентация %d)", p.Name, orientation)
+	}
+	if cmyk {
+		report.AddFinding(Finding{Page: p.Name, Problem: "cmyk", Detail: "CMYK → RGB", Action: "normalized"})
+		log.Printf("🎨 Страница %s: CMYK → RGB", p.Name)
+	}
+	if len(profile) > 0 {
+		// The pixels are only rotated, so the profile still describes them
+		// unless the colour model changed.
+		if !cmyk && isGrayModel(img) == isGrayModel(upright) {
+			if err := embedProfile(p.Path, profile, embed); err != nil {
+				return err
+			}
+		} else if !srgb {
+			// Without a colour management engine the profile cannot be
+			// applied; colours may shift slightly.
+			report.AddFinding(Finding{Page: p.Name, Problem: "icc", Detail: "ICC-профиль отброшен без преобразования цветов", Action: "normalized"})
+			log.Printf("🎨 Страница %s: ICC-профиль отброшен без преобразования цветов", p.Name)
+		}
+	}
+	return nil
+}
+
+func embedProfile(path string, profile []byte, embed func(data, profile []byte) []byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, embed(data, profile), 0644)
+}
+
+func isGrayModel(img image.Image) bool {
+	m := img.ColorModel()
+	return m == color.GrayModel || m == color.Gray16Model
+}
+
+// normalizeImage applies an EXIF orientation (1-8) and converts the result to
+// RGBA, or to grey when every pixel is neutral (typical for CMYK scans of
+// black-and-white pages).
+func normalizeImage(img image.Image, orientation int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	_, isGray := img.(*image.Gray)
+	var dst draw.Image
+	if isGray {
+		dst = image.NewGray(image.Rect(0, 0, dw, dh))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, dw, dh))
+	}
+
+	neutral := true
+	for sy := 0; sy < h; sy++ {
+		for sx := 0; sx < w; sx++ {
+			c := img.At(b.Min.X+sx, b.Min.Y+sy)
+			dx, dy := orientPoint(orientation, sx, sy, w, h)
+			dst.Set(dx, dy, c)
+			if neutral && !isGray {
+				r, g, bl, _ := c.RGBA()
+				neutral = r>>8 == g>>8 && g>>8 == bl>>8
+			}
+		}
+	}
+
+	if isGray || !neutral {
+		return dst
+	}
+	gray := image.NewGray(dst.Bounds())
+	draw.Draw(gray, gray.Bounds(), dst, image.Point{}, draw.Src)
+	return gray
+}
+
+// orientPoint maps a source pixel to its position in the upright image.
+func orientPoint(orientation, x, y, w, h int) (int, int) {
+	switch orientation {
+	case 2: // mirrored horizontally
+		return w - 1 - x, y
+	case 3: // rotated 180°
+		return w - 1 - x, h - 1 - y
+	case 4: // mirrored vertically
+		return x, h - 1 - y
+	case 5: // transposed
+		return y, x
+	case 6: // needs 90° clockwise rotation
+		return h - 1 - y, x
+	case 7: // transversed
+		return h - 1 - y, w - 1 - x
+	case 8: // needs 90° counter-clockwise rotation
+		return y, w - 1 - x
+	}
+	return x, y
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment with a little-endian IFD0 holding the
+// orientation tag and, optionally, a GPS IFD pointer.
+func exifSegment(orientation uint16, gps bool) []byte {
+	entries := 1
+	if gps {
+		entries++
+	}
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(entries))
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	if gps {
+		tiff = binary.LittleEndian.AppendUint16(tiff, 0x8825)
+		tiff = binary.LittleEndian.AppendUint16(tiff, 4)
+		tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+		tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	}
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func jpegWithExif(t *testing.T, path string, width, height int, orientation uint16, gps bool) {
+	t.Helper()
+	writeJPEG(t, path, width, height)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read jpeg: %v", err)
+	}
+	withExif := append([]byte{}, data[:2]...)
+	withExif = append(withExif, exifSegment(orientation, gps)...)
+	withExif = append(withExif, data[2:]...)
+	if err := os.WriteFile(path, withExif, 0o644); err != nil {
+		t.Fatalf("write jpeg: %v", err)
+	}
+}
+
+func TestNormalizePageRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "001.jpg")
+	jpegWithExif(t, path, 40, 20, 6, true)
+
+	page := &Page{Path: path, Name: "001.jpg", Width: 40, Height: 20, OriginalWidth: 40, OriginalHeight: 20}
+	report := &VolumeReport{}
+	if err := NormalizePage(page, report); err != nil {
+		t.Fatalf("NormalizePage error: %v", err)
+	}
+
+	w, h, err := ImageSize(path)
+	if err != nil {
+		t.Fatalf("ImageSize error: %v", err)
+	}
+	if w != 20 || h != 40 || page.Width != 20 || page.Height != 40 {
+		t.Fatalf("size after rotation = %dx%d (page %dx%d), want 20x40", w, h, page.Width, page.Height)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read page: %v", err)
+	}
+	if bytes.Contains(data, []byte("Exif")) {
+		t.Fatal("EXIF should be removed")
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Problem != "exif-orientation" {
+		t.Fatalf("unexpected findings: %+v", report.Findings)
+	}
+}
+
+func TestNormalizePageStripsMetadataLosslessly(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.jpg")
+	writeJPEG(t, clean, 16, 16)
+	tagged := filepath.Join(dir, "tagged.jpg")
+	jpegWithExif(t, tagged, 16, 16, 1, true)
+
+	page := &Page{Path: tagged, Name: "tagged.jpg", Width: 16, Height: 16}
+	if err := NormalizePage(page, &VolumeReport{}); err != nil {
+		t.Fatalf("NormalizePage error: %v", err)
+	}
+
+	want, err := os.ReadFile(clean)
+	if err != nil {
+		t.Fatalf("read clean: %v", err)
+	}
+	got, err := os.ReadFile(tagged)
+	if err != nil {
+		t.Fatalf("read tagged: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("stripped file should match the original JPEG byte for byte")
+	}
+}
+
+// withICC inserts an APP2 ICC_PROFILE segment whose description is name
+// after the SOI marker of a JPEG.
+func withICC(t *testing.T, path, name string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read jpeg: %v", err)
+	}
+	payload := append([]byte("ICC_PROFILE\x00\x01\x01"), name...)
+	segment := []byte{0xFF, 0xE2}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	if err := os.WriteFile(path, insertJPEGSegments(data, segment), 0o644); err != nil {
+		t.Fatalf("write jpeg: %v", err)
+	}
+}
+
+func TestNormalizePageICCProfile(t *testing.T) {
+	dir := t.TempDir()
+	colour := filepath.Join(dir, "001.jpg")
+	jpegWithExif(t, colour, 40, 20, 6, false)
+	withICC(t, colour, "Adobe RGB (1998)")
+
+	report := &VolumeReport{}
+	if err := NormalizePage(&Page{Path: colour, Name: "001.jpg", Width: 40, Height: 20}, report); err != nil {
+		t.Fatalf("NormalizePage error: %v", err)
+	}
+	data, err := os.ReadFile(colour)
+	if err != nil {
+		t.Fatalf("read page: %v", err)
+	}
+	if !bytes.Contains(data, []byte("ICC_PROFILE\x00\x01\x01Adobe RGB (1998)")) {
+		t.Fatal("rotated colour page should keep its ICC profile")
+	}
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("rotated page does not decode: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Problem != "exif-orientation" {
+		t.Fatalf("unexpected findings: %+v", report.Findings)
+	}
+
+	// A neutral page is saved as greyscale, which an RGB profile cannot
+	// describe.
+	gray := filepath.Join(dir, "002.jpg")
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{Y: 128}), image.Point{}, draw.Src)
+	writeImageFile(t, gray, img)
+	data, _ = os.ReadFile(gray)
+	if err := os.WriteFile(gray, insertJPEGSegments(data, exifSegment(3, false)), 0o644); err != nil {
+		t.Fatalf("write jpeg: %v", err)
+	}
+	withICC(t, gray, "Adobe RGB (1998)")
+
+	report = &VolumeReport{}
+	if err := NormalizePage(&Page{Path: gray, Name: "002.jpg", Width: 16, Height: 16}, report); err != nil {
+		t.Fatalf("NormalizePage error: %v", err)
+	}
+	if data, _ := os.ReadFile(gray); bytes.Contains(data, []byte("ICC_PROFILE")) {
+		t.Fatal("greyscale page should not carry an RGB profile")
+	}
+	if len(report.Findings) != 2 || report.Findings[1].Problem != "icc" {
+		t.Fatalf("dropped profile should be reported, got %+v", report.Findings)
+	}
+}
+
+func TestNormalizeImageOrientations(t *testing.T) {
+	// A 3x2 image with a single marked pixel in the top-left corner.
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	src.SetGray(0, 0, color.Gray{Y: 255})
+
+	cases := []struct {
+		orientation int
+		w, h        int
+		x, y        int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+	for _, tc := range cases {
+		got := normalizeImage(src, tc.orientation).(*image.Gray)
+		if got.Bounds().Dx() != tc.w || got.Bounds().Dy() != tc.h {
+			t.Fatalf("orientation %d: size %v", tc.orientation, got.Bounds())
+		}
+		if got.GrayAt(tc.x, tc.y).Y != 255 {
+			t.Fatalf("orientation %d: marked pixel not at %d,%d", tc.orientation, tc.x, tc.y)
+		}
+	}
+}
+
+func TestNormalizeImageCMYKToGray(t *testing.T) {
+	src := image.NewCMYK(image.Rect(0, 0, 4, 4))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i+3] = 200 // black ink only
+	}
+
+	if _, ok := normalizeImage(src, 1).(*image.Gray); !ok {
+		t.Fatal("neutral CMYK page should become greyscale")
+	}
+
+	src.Pix[0] = 255 // a cyan pixel
+	if _, ok := normalizeImage(src, 1).(*image.RGBA); !ok {
+		t.Fatal("coloured CMYK page should become RGBA")
+	}
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	GranularityVolume  = "volume"  // one book per volume folder
+	GranularityChapter = "chapter" // one book per chapter
+	GranularityOmnibus = "omnibus" // volumes merged into one book
+)
+
+// omnibusGroups splits the volumes into runs of size volumes; size 0 puts
+// all of them into a single book.
+func omnibusGroups(volumes []string, size int) [][]string {
+	if size <= 0 || size >= len(volumes) {
+		if len(volumes) == 0 {
+			return nil
+		}
+		return [][]string{volumes}
+	}
+	var groups [][]string
+	for start := 0; start < len(volumes); start += size {
+		groups = append(groups, volumes[start:min(start+size, len(volumes))])
+	}
+	return groups
+}
+
+func omnibusLabel(group []string, whole bool) string {
+	switch {
+	case whole:
+		return "Omnibus"
+	case len(group) == 1:
+		return volumeFileLabel(group[0])
+	}
+	first, last := parseVolumeName(group[0]).Volume, parseVolumeName(group[len(group)-1]).Volume
+	if first != "" && last != "" {
+		return "Vol_" + padNumber(first, 2) + "-" + padNumber(last, 2)
+	}
+	return group[0] + "-" + group[len(group)-1]
+}
+
+// omnibusTitle describes a run of volumes: "Тома 1–3".
+func omnibusTitle(group []string) string {
+	if len(group) == 1 {
+		return volumeTitle(group[0])
+	}
+	first, last := group[0], group[len(group)-1]
+	if v := parseVolumeName(first).Volume; v != "" {
+		first = v
+	}
+	if v := parseVolumeName(last).Volume; v != "" {
+		last = v
+	}
+	return fmt.Sprintf("Тома %s–%s", first, last)
+}
+
+// convertOmnibus merges the pages of several volumes into one book. Pages are
+// stored under their volume folder and the first page of each volume carries
+// a bookmark.
+func convertOmnibus(mangaRoot string, volumes []string, meta *Metadata, report *VolumeReport, whole bool) error {
+	mangaName := seriesName(filepath.Base(mangaRoot))
+
+	bookMeta := *meta
+	if whole {
+		bookMeta.Title = fmt.Sprintf("%s — Омнибус", meta.Series.Title)
+	} else {
+		bookMeta.Title = fmt.Sprintf("%s — %s", meta.Series.Title, omnibusTitle(volumes))
+	}
+
+	var pages []*Page
+	for _, volume := range volumes {
+		volPages, err := LoadPages(filepath.Join(mangaRoot, volume))
+		if err != nil {
+			return fmt.Errorf("чтение страниц тома %s: %w", volume, err)
+		}
+		for _, p := range volPages {
+			p.Name = volume + "/" + p.Name
+		}
+		volPages, err = ProcessPages(volPages, report)
+		if err != nil {
+			return fmt.Errorf("обработка страниц тома %s: %w", volume, err)
+		}
+		if len(volPages) > 0 {
+			volPages[0].Bookmark = volumeTitle(volume)
+		}
+		pages = append(pages, volPages...)
+	}
+
+	name := bookName{Folder: mangaName, Label: omnibusLabel(volumes, whole)}
+	return writeOutputs(pages, &bookMeta, name, report)
+}
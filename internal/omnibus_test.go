@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOmnibusGroups(t *testing.T) {
+	volumes := []string{"1", "2", "3", "4", "5"}
+	cases := []struct {
+		size int
+		want [][]string
+	}{
+		{0, [][]string{{"1", "2", "3", "4", "5"}}},
+		{5, [][]string{{"1", "2", "3", "4", "5"}}},
+		{2, [][]string{{"1", "2"}, {"3", "4"}, {"5"}}},
+	}
+	for _, tc := range cases {
+		if got := omnibusGroups(volumes, tc.size); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("omnibusGroups(size=%d) = %v, want %v", tc.size, got, tc.want)
+		}
+	}
+	if got := omnibusGroups(nil, 0); got != nil {
+		t.Fatalf("omnibusGroups(nil) = %v, want nil", got)
+	}
+
+	if got := omnibusLabel([]string{"1", "2"}, true); got != "Omnibus" {
+		t.Fatalf("whole label = %q", got)
+	}
+	if got := omnibusLabel([]string{"Том 3", "Том 4"}, false); got != "Vol_03-04" {
+		t.Fatalf("group label = %q", got)
+	}
+}
+
+func TestConvertOmnibus(t *testing.T) {
+	tmp := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	mangaRoot := filepath.Join(tmp, "TestManga")
+	writeJPEG(t, filepath.Join(mangaRoot, "1", "001.jpg"), 10, 10)
+	writeJPEG(t, filepath.Join(mangaRoot, "1", "002.jpg"), 10, 10)
+	writeJPEG(t, filepath.Join(mangaRoot, "2", "001.jpg"), 10, 10)
+
+	report := NewJobReport("test.zip").Volume("Omnibus")
+	meta := &Metadata{Title: "Test Title", Series: Series{Title: "Test Title"}}
+	if err := convertOmnibus(mangaRoot, []string{"1", "2"}, meta, report, true); err != nil {
+		t.Fatalf("convertOmnibus error: %v", err)
+	}
+
+	out := filepath.Join("output", "cbz", "Test Title", "TestManga__Omnibus.cbz")
+	files, contents := readZipEntries(t, out)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	want := []string{"1/001.jpg", "1/002.jpg", "2/001.jpg", "ComicInfo.xml"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("entries = %v, want %v", names, want)
+	}
+
+	info := contents["ComicInfo.xml"]
+	if !strings.Contains(info, "Test Title — Омнибус") {
+		t.Fatalf("ComicInfo.xml missing title, got %s", info)
+	}
+	for _, page := range []string{`<Page Image="0" Bookmark="Том 1"`, `<Page Image="2" Bookmark="Том 2"`} {
+		if !strings.Contains(info, page) {
+			t.Fatalf("ComicInfo.xml missing %s, got %s", page, info)
+		}
+	}
+	if strings.Contains(info, `<Page Image="1" Bookmark`) {
+		t.Fatalf("unexpected bookmark on a middle page: %s", info)
+	}
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Page is a single image of a volume on its way into the output archive.
+type Page struct {
+	Path string // file on disk
+	Name string // entry name inside the archive
+
+	Width  int
+	Height int
+	// OriginalWidth and OriginalHeight keep the size of the source scan
+	// before any stage (crop, split, ...) changed it.
+	OriginalWidth  int
+	OriginalHeight int
+	// DoublePage marks a spread kept whole.
+	DoublePage bool
+	// Bookmark names the volume or chapter that starts at this page.
+	Bookmark string
+	// FrontCover marks the series cover added in front of the book.
+	FrontCover bool
+	// Panels are the detected comic panels in reading order, in image
+	// coordinates. Empty when detection is off or found a single panel.
+	Panels []image.Rectangle
+	// Problem is set for broken pages packed under the "warn" validation
+	// policy; image stages leave such pages alone.
+	Problem string
+}
+
+// LoadPages lists the images of a volume folder in reading order.
+func LoadPages(folder string) ([]*Page, error) {
+	images, err := ListImages(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]*Page, 0, len(images))
+	for _, path := range images {
+		rel, err := filepath.Rel(folder, path)
+		if err != nil {
+			return nil, err
+		}
+		page := &Page{Path: path, Name: filepath.ToSlash(rel)}
+		// Broken pages keep a zero size here and are reported by ValidatePages.
+		w, h, _ := ImageSize(path)
+		page.Width, page.Height = w, h
+		page.OriginalWidth, page.OriginalHeight = w, h
+		pages = append(pages, page)
+	}
+	// "page2" and "Глава 2" come before "page10" and "Глава 10".
+	slices.SortStableFunc(pages, func(a, b *Page) int { return compareNatural(a.Name, b.Name) })
+	return pages, nil
+}
+
+// ProcessPages runs the enabled image stages over the pages of a volume.
+func ProcessPages(pages []*Page, report *VolumeReport) ([]*Page, error) {
+	pages, err := ValidatePages(pages, Config.Validation.Policy, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if Config.Normalize.Enabled {
+		err := forEachPage(pages, func(_ int, p *Page) error {
+			if p.Problem != "" {
+				return nil
+			}
+			if err := NormalizePage(p, report); err != nil {
+				return fmt.Errorf("нормализация %s: %w", p.Name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if Config.Dedupe.Enabled {
+		pages, err = RemoveDuplicates(pages, Config.Dedupe, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if Config.Blank.Enabled {
+		pages, err = RemoveBlankPages(pages, Config.Blank, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if Config.Crop.Enabled {
+		err := forEachPage(pages, func(_ int, p *Page) error {
+			if p.Problem != "" {
+				return nil
+			}
+			if err := CropPage(p, Config.Crop); err != nil {
+				return fmt.Errorf("обрезка %s: %w", p.Name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+func decodeImage(path string) (image.Image, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	return image.Decode(file)
+}
+
+// encodeImage writes img to path in the format implied by the extension.
+func encodeImage(path string, img image.Image) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		err = png.Encode(out, img)
+	default:
+		err = jpeg.Encode(out, img, &jpeg.Options{Quality: Config.JPEGQuality})
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// luma returns the 8-bit luminance of a pixel, with fast paths for the
+// image types produced by the standard decoders.
+func luma(img image.Image, x, y int) uint8 {
+	switch m := img.(type) {
+	case *image.YCbCr:
+		return m.Y[m.YOffset(x, y)]
+	case *image.Gray:
+		return m.Pix[m.PixOffset(x, y)]
+	case *image.RGBA:
+		i := m.PixOffset(x, y)
+		return rgbLuma(uint32(m.Pix[i]), uint32(m.Pix[i+1]), uint32(m.Pix[i+2]))
+	case *image.NRGBA:
+		i := m.PixOffset(x, y)
+		return rgbLuma(uint32(m.Pix[i]), uint32(m.Pix[i+1]), uint32(m.Pix[i+2]))
+	}
+	r, g, b, _ := img.At(x, y).RGBA()
+	return rgbLuma(r>>8, g>>8, b>>8)
+}
+
+func rgbLuma(r, g, b uint32) uint8 {
+	// Same weights as color.GrayModel.
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 16)
+}
@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"encoding/json"
+	"image"
+	"os"
+)
+
+const (
+	// panelInk is the luminance distance from the page background from which
+	// a pixel counts as drawn.
+	panelInk = 48
+	// panelNoise is the share of drawn pixels a gutter line may still hold.
+	panelNoise = 0.005
+	// Panels narrower or lower than these shares of the page are dropped
+	// (page numbers, stray marks).
+	panelMinWidth  = 0.08
+	panelMinHeight = 0.05
+)
+
+// inkMask is a binarised page: true where something is drawn.
+type inkMask struct {
+	w, h int
+	ink  []bool
+}
+
+func newInkMask(img image.Image) *inkMask {
+	b := img.Bounds()
+	m := &inkMask{w: b.Dx(), h: b.Dy(), ink: make([]bool, b.Dx()*b.Dy())}
+	if m.w == 0 || m.h == 0 {
+		return m
+	}
+
+	var border []uint8
+	for x := 0; x < m.w; x++ {
+		border = append(border, luma(img, b.Min.X+x, b.Min.Y), luma(img, b.Min.X+x, b.Max.Y-1))
+	}
+	for y := 0; y < m.h; y++ {
+		border = append(border, luma(img, b.Min.X, b.Min.Y+y), luma(img, b.Max.X-1, b.Min.Y+y))
+	}
+	bg := medianLuma(border)
+
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			m.ink[y*m.w+x] = absDiff(luma(img, b.Min.X+x, b.Min.Y+y), bg) > panelInk
+		}
+	}
+	return m
+}
+
+// lines returns for each row (or column) of r whether it is a gutter.
+func (m *inkMask) lines(r image.Rectangle, rows bool) []bool {
+	outer, inner := r.Min.Y, r.Max.Y
+	from, to := r.Min.X, r.Max.X
+	if !rows {
+		outer, inner = r.Min.X, r.Max.X
+		from, to = r.Min.Y, r.Max.Y
+	}
+	allowed := int(float64(to-from) * panelNoise)
+
+	empty := make([]bool, inner-outer)
+	for i := range empty {
+		count := 0
+		for j := from; j < to && count <= allowed; j++ {
+			x, y := j, outer+i
+			if !rows {
+				x, y = outer+i, j
+			}
+			if m.ink[y*m.w+x] {
+				count++
+			}
+		}
+		empty[i] = count <= allowed
+	}
+	return empty
+}
+
+// segments splits r along rows or columns at gutters at least minGap wide and
+// returns the non-empty parts in top-to-bottom or left-to-right order.
+func (m *inkMask) segments(r image.Rectangle, rows bool, minGap int) []image.Rectangle {
+	empty := m.lines(r, rows)
+	var parts []image.Rectangle
+	start, gap := -1, 0
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if rows {
+			parts = append(parts, image.Rect(r.Min.X, r.Min.Y+start, r.Max.X, r.Min.Y+end))
+		} else {
+			parts = append(parts, image.Rect(r.Min.X+start, r.Min.Y, r.Min.X+end, r.Max.Y))
+		}
+		start = -1
+	}
+	for i, e := range empty {
+		if !e {
+			if start < 0 {
+				start = i
+			}
+			gap = 0
+			continue
+		}
+		gap++
+		if start >= 0 && gap >= minGap {
+			flush(i - gap + 1)
+		}
+	}
+	if start >= 0 {
+		end := len(empty)
+		for end > start && empty[end-1] {
+			end--
+		}
+		flush(end)
+	}
+	return parts
+}
+
+// DetectPanels finds comic panels by recursive gutter cuts on the binarised
+// page (an XY-cut): the page is split into horizontal bands at empty rows,
+// every band into columns at empty columns, and so on until no gutter is
+// left. Panels come in reading order: top to bottom, then right to left for
+// manga or left to right otherwise. A page that is a single panel yields none.
+func DetectPanels(img image.Image, direction string) []image.Rectangle {
+	m := newInkMask(img)
+	if m.w == 0 || m.h == 0 {
+		return nil
+	}
+	minGap := max(3, min(m.w, m.h)/200)
+
+	var panels []image.Rectangle
+	var cut func(r image.Rectangle, depth int)
+	cut = func(r image.Rectangle, depth int) {
+		if depth > 16 {
+			panels = append(panels, r)
+			return
+		}
+		if bands := m.segments(r, true, minGap); len(bands) > 1 {
+			for _, band := range bands {
+				cut(band, depth+1)
+			}
+			return
+		}
+		if cols := m.segments(r, false, minGap); len(cols) > 1 {
+			if direction == DirectionRTL {
+				for i := len(cols) - 1; i >= 0; i-- {
+					cut(cols[i], depth+1)
+				}
+			} else {
+				for _, col := range cols {
+					cut(col, depth+1)
+				}
+			}
+			return
+		}
+		// Trim the gutters around a leaf.
+		if bands := m.segments(r, true, 1); len(bands) > 0 {
+			r.Min.Y, r.Max.Y = bands[0].Min.Y, bands[len(bands)-1].Max.Y
+		}
+		if cols := m.segments(r, false, 1); len(cols) > 0 {
+			r.Min.X, r.Max.X = cols[0].Min.X, cols[len(cols)-1].Max.X
+		}
+		panels = append(panels, r)
+	}
+	cut(image.Rect(0, 0, m.w, m.h), 0)
+
+	var out []image.Rectangle
+	for _, p := range panels {
+		if float64(p.Dx()) >= float64(m.w)*panelMinWidth && float64(p.Dy()) >= float64(m.h)*panelMinHeight {
+			out = append(out, p.Add(img.Bounds().Min))
+		}
+	}
+	if len(out) < 2 {
+		return nil
+	}
+	return out
+}
+
+// detectPagePanels fills Page.Panels for every page.
+func detectPagePanels(pages []*Page, direction string) error {
+	return forEachPage(pages, func(_ int, p *Page) error {
+		if p.Problem != "" {
+			return nil
+		}
+		img, _, err := decodeImage(p.Path)
+		if err != nil {
+			return err
+		}
+		p.Panels = DetectPanels(img, direction)
+		return nil
+	})
+}
+
+type panelSidecar struct {
+	Pages []panelSidecarPage `json:"pages"`
+}
+
+type panelSidecarPage struct {
+	Name   string   `json:"name"`
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Panels [][4]int `json:"panels"` // x, y, width, height
+}
+
+// writePanelSidecar stores the detected panels next to an output book.
+func writePanelSidecar(pages []*Page, path string) error {
+	var sidecar panelSidecar
+	for _, p := range pages {
+		entry := panelSidecarPage{Name: p.Name, Width: p.Width, Height: p.Height, Panels: [][4]int{}}
+		for _, r := range p.Panels {
+			entry.Panels = append(entry.Panels, [4]int{r.Min.X, r.Min.Y, r.Dx(), r.Dy()})
+		}
+		sidecar.Pages = append(sidecar.Pages, entry)
+	}
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// threePanelPage draws a wide panel on top and two panels below it.
+func threePanelPage() (*image.Gray, []image.Rectangle) {
+	img := image.NewGray(image.Rect(0, 0, 200, 300))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	panels := []image.Rectangle{
+		image.Rect(10, 10, 190, 140),
+		image.Rect(10, 160, 95, 290),
+		image.Rect(105, 160, 190, 290),
+	}
+	for _, r := range panels {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.SetGray(x, y, color.Gray{Y: 40})
+			}
+		}
+	}
+	return img, panels
+}
+
+func TestDetectPanelsReadingOrder(t *testing.T) {
+	img, panels := threePanelPage()
+	top, left, right := panels[0], panels[1], panels[2]
+
+	rtl := DetectPanels(img, DirectionRTL)
+	want := []image.Rectangle{top, right, left}
+	if len(rtl) != len(want) {
+		t.Fatalf("rtl panels = %v, want %v", rtl, want)
+	}
+	for i := range want {
+		if rtl[i] != want[i] {
+			t.Fatalf("rtl panels = %v, want %v", rtl, want)
+		}
+	}
+
+	ltr := DetectPanels(img, DirectionLTR)
+	if len(ltr) != 3 || ltr[1] != left || ltr[2] != right {
+		t.Fatalf("ltr panels = %v", ltr)
+	}
+}
+
+func TestDetectPanelsSinglePanel(t *testing.T) {
+	img := framedPage(100, 150, image.Rect(5, 5, 95, 145))
+	if got := DetectPanels(img, DirectionRTL); got != nil {
+		t.Fatalf("single panel page should yield no panels, got %v", got)
+	}
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"os"
+	"path"
+)
+
+type PartSettings struct {
+	// MaxMB and MaxPages limit a single output file; 0 means no limit. Page
+	// file sizes are used as an estimate of the archive size.
+	MaxMB    int `json:"max_mb"`
+	MaxPages int `json:"max_pages"`
+}
+
+// splitParts divides a volume into parts that fit the limits. When pages are
+// grouped in chapter folders, parts break between chapters; a chapter that
+// does not fit on its own is split between pages.
+func splitParts(pages []*Page, cfg PartSettings) ([][]*Page, error) {
+	if cfg.MaxMB <= 0 && cfg.MaxPages <= 0 {
+		return [][]*Page{pages}, nil
+	}
+
+	sizes := make(map[*Page]int64, len(pages))
+	for _, p := range pages {
+		info, err := os.Stat(p.Path)
+		if err != nil {
+			return nil, err
+		}
+		sizes[p] = info.Size()
+	}
+	maxBytes := int64(cfg.MaxMB) << 20
+	fits := func(count int, size int64) bool {
+		return (cfg.MaxPages <= 0 || count <= cfg.MaxPages) && (maxBytes <= 0 || size <= maxBytes)
+	}
+	sizeOf := func(group []*Page) int64 {
+		var total int64
+		for _, p := range group {
+			total += sizes[p]
+		}
+		return total
+	}
+
+	var parts [][]*Page
+	var current []*Page
+	var currentSize int64
+	flush := func() {
+		if len(current) > 0 {
+			parts = append(parts, current)
+			current, currentSize = nil, 0
+		}
+	}
+
+	chapters := groupByChapter(pages)
+	if len(chapters) == 1 && path.Dir(pages[0].Name) == "." {
+		// No chapter information: every page is its own unit.
+		chapters = nil
+		for _, p := range pages {
+			chapters = append(chapters, []*Page{p})
+		}
+	}
+
+	for _, chapter := range chapters {
+		size := sizeOf(chapter)
+		if fits(len(current)+len(chapter), currentSize+size) {
+			current = append(current, chapter...)
+			currentSize += size
+			continue
+		}
+		flush()
+		if fits(len(chapter), size) {
+			current, currentSize = append(current, chapter...), size
+			continue
+		}
+		// The chapter alone is too big: fall back to page boundaries.
+		for _, p := range chapter {
+			if len(current) > 0 && !fits(len(current)+1, currentSize+sizes[p]) {
+				flush()
+			}
+			current = append(current, p)
+			currentSize += sizes[p]
+		}
+	}
+	flush()
+	return parts, nil
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sizedPages creates files of the given sizes (in KB) named after their
+// chapter folder.
+func sizedPages(t *testing.T, names []string, sizeKB int) []*Page {
+	t.Helper()
+	dir := t.TempDir()
+	var pages []*Page
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, sizeKB<<10), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		pages = append(pages, &Page{Path: path, Name: name})
+	}
+	return pages
+}
+
+func partNames(parts [][]*Page) [][]string {
+	var out [][]string
+	for _, part := range parts {
+		var names []string
+		for _, p := range part {
+			names = append(names, p.Name)
+		}
+		out = append(out, names)
+	}
+	return out
+}
+
+func TestSplitPartsNoLimit(t *testing.T) {
+	pages := sizedPages(t, []string{"1.jpg", "2.jpg"}, 1)
+	parts, err := splitParts(pages, PartSettings{})
+	if err != nil {
+		t.Fatalf("splitParts error: %v", err)
+	}
+	if len(parts) != 1 || len(parts[0]) != 2 {
+		t.Fatalf("unexpected parts: %v", partNames(parts))
+	}
+}
+
+func TestSplitPartsByPagesAtChapterBoundaries(t *testing.T) {
+	pages := sizedPages(t, []string{
+		"ch1/1.jpg", "ch1/2.jpg",
+		"ch2/1.jpg", "ch2/2.jpg",
+		"ch3/1.jpg", "ch3/2.jpg", "ch3/3.jpg", "ch3/4.jpg", "ch3/5.jpg",
+	}, 1)
+
+	parts, err := splitParts(pages, PartSettings{MaxPages: 4})
+	if err != nil {
+		t.Fatalf("splitParts error: %v", err)
+	}
+	got := partNames(parts)
+	want := [][]string{
+		{"ch1/1.jpg", "ch1/2.jpg", "ch2/1.jpg", "ch2/2.jpg"},
+		// ch3 is too long for one part and is split between pages.
+		{"ch3/1.jpg", "ch3/2.jpg", "ch3/3.jpg", "ch3/4.jpg"},
+		{"ch3/5.jpg"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parts = %v, want %v", got, want)
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) || got[i][0] != want[i][0] {
+			t.Fatalf("parts = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSplitPartsBySize(t *testing.T) {
+	// 300 KB pages with a 1 MB limit: three pages per part.
+	pages := sizedPages(t, []string{"1.jpg", "2.jpg", "3.jpg", "4.jpg", "5.jpg"}, 300)
+
+	parts, err := splitParts(pages, PartSettings{MaxMB: 1})
+	if err != nil {
+		t.Fatalf("splitParts error: %v", err)
+	}
+	if len(parts) != 2 || len(parts[0]) != 3 || len(parts[1]) != 2 {
+		t.Fatalf("unexpected parts: %v", partNames(parts))
+	}
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SeriesPin ties a series to provider IDs so it is never searched by name.
+// Exactly one of Name and Pattern is set.
+type SeriesPin struct {
+	// Name is compared with the series folder name ignoring case,
+	// punctuation and release tags.
+	Name string `json:"name,omitempty"`
+	// Pattern is a regular expression matched against the series name.
+	Pattern string `json:"pattern,omitempty"`
+
+	Shikimori int    `json:"shikimori,omitempty"`
+	AniList   int    `json:"anilist,omitempty"`
+	MangaDex  string `json:"mangadex,omitempty"`
+}
+
+func (p SeriesPin) validate() error {
+	if (p.Name == "") == (p.Pattern == "") {
+		return errors.New("нужно задать ровно одно из name и pattern")
+	}
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("pattern: %w", err)
+		}
+	}
+	if p.Shikimori == 0 && p.AniList == 0 && p.MangaDex == "" {
+		return errors.New("не задан ни один ID")
+	}
+	return nil
+}
+
+// LoadPins reads the pin table. A missing file is an empty table.
+func LoadPins(path string) ([]SeriesPin, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pins []SeriesPin
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	for i, pin := range pins {
+		if err := pin.validate(); err != nil {
+			return nil, fmt.Errorf("%s, запись %d: %w", path, i+1, err)
+		}
+	}
+	return pins, nil
+}
+
+// SavePin adds a pin to the table or merges its IDs into the one with the
+// same name or pattern, so pinning another provider keeps the earlier IDs.
+func SavePin(path string, pin SeriesPin) error {
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	pins, err := LoadPins(path)
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i, p := range pins {
+		if p.Pattern == pin.Pattern && seriesKey(p.Name) == seriesKey(pin.Name) {
+			pin.Shikimori = cmp.Or(pin.Shikimori, p.Shikimori)
+			pin.AniList = cmp.Or(pin.AniList, p.AniList)
+			pin.MangaDex = cmp.Or(pin.MangaDex, p.MangaDex)
+			pins[i], replaced = pin, true
+		}
+	}
+	if !replaced {
+		pins = append(pins, pin)
+	}
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// findPin returns the pin of a series: a name match wins over patterns,
+// otherwise the first matching pattern in file order.
+func findPin(series string) *SeriesPin {
+	pins, err := LoadPins(Config.Pins)
+	if err != nil {
+		log.Printf("⚠️ Таблица привязок не загружена, ищем по имени: %v", err)
+		return nil
+	}
+	key := seriesKey(series)
+	for i := range pins {
+		if pins[i].Name != "" && seriesKey(pins[i].Name) == key {
+			return &pins[i]
+		}
+	}
+	for i := range pins {
+		if pins[i].Pattern != "" && regexp.MustCompile(pins[i].Pattern).MatchString(series) {
+			return &pins[i]
+		}
+	}
+	return nil
+}
+
+// seriesKey normalizes a series name for comparison: "[Group] One_Piece
+// (Digital)" and "one piece" give the same key.
+func seriesKey(name string) string {
+	if name == "" {
+		return ""
+	}
+	name = strings.ToLower(norm.NFC.String(seriesName(name)))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// LookupMetadata is the entry point of the metadata layer: a pinned series
+// is fetched by ID, any other one is searched by name.
+func LookupMetadata(series string) (*Metadata, error) {
+	if pin := findPin(series); pin != nil {
+		switch {
+		case pin.Shikimori != 0:
+			log.Printf("📌 %s привязана к Shikimori #%d", series, pin.Shikimori)
+			return FetchShikimoriByID(pin.Shikimori, series)
+		case pin.AniList != 0:
+			log.Printf("📌 %s привязана к AniList #%d", series, pin.AniList)
+			return FetchAniListByID(pin.AniList, series)
+		}
+	}
+	return FetchMetadata(series)
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func usePins(t *testing.T, pins string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pins.json")
+	if err := os.WriteFile(path, []byte(pins), 0o644); err != nil {
+		t.Fatalf("write pins: %v", err)
+	}
+	original := Config
+	settings := *Config
+	settings.Pins = path
+	Config = &settings
+	t.Cleanup(func() { Config = original })
+}
+
+func TestSeriesKey(t *testing.T) {
+	if a, b := seriesKey("[Group] One_Piece (Digital)"), seriesKey("one piece"); a != b {
+		t.Fatalf("seriesKey mismatch: %q vs %q", a, b)
+	}
+	if got := seriesKey("Ванпанчмен!"); got != "ванпанчмен" {
+		t.Fatalf("seriesKey = %q", got)
+	}
+}
+
+func TestFindPin(t *testing.T) {
+	usePins(t, `[
+		{"pattern": "(?i)^one.?piece", "mangadex": "pattern"},
+		{"name": "One Piece", "shikimori": 13}
+	]`)
+
+	if pin := findPin("One_Piece"); pin == nil || pin.Shikimori != 13 {
+		t.Fatalf("name pin should win over pattern, got %+v", pin)
+	}
+	if pin := findPin("OnePiece Colored"); pin == nil || pin.MangaDex != "pattern" {
+		t.Fatalf("pattern pin not matched, got %+v", pin)
+	}
+	if pin := findPin("Naruto"); pin != nil {
+		t.Fatalf("unexpected pin %+v", pin)
+	}
+}
+
+func TestLoadPinsValidation(t *testing.T) {
+	for _, pins := range []string{
+		`[{"name": "A"}]`,
+		`[{"name": "A", "pattern": "A", "shikimori": 1}]`,
+		`[{"pattern": "(", "shikimori": 1}]`,
+	} {
+		path := filepath.Join(t.TempDir(), "pins.json")
+		if err := os.WriteFile(path, []byte(pins), 0o644); err != nil {
+			t.Fatalf("write pins: %v", err)
+		}
+		if _, err := LoadPins(path); err == nil {
+			t.Fatalf("pins %s should be rejected", pins)
+		}
+	}
+	if pins, err := LoadPins(filepath.Join(t.TempDir(), "missing.json")); err != nil || pins != nil {
+		t.Fatalf("missing file should be an empty table, got %v %v", pins, err)
+	}
+}
+
+func TestSavePin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.json")
+	if err := SavePin(path, SeriesPin{Name: "One Piece", Shikimori: 1}); err != nil {
+		t.Fatalf("SavePin error: %v", err)
+	}
+	if err := SavePin(path, SeriesPin{Pattern: "^Naruto", AniList: 2}); err != nil {
+		t.Fatalf("SavePin error: %v", err)
+	}
+	if err := SavePin(path, SeriesPin{Name: "one_piece", Shikimori: 13}); err != nil {
+		t.Fatalf("SavePin error: %v", err)
+	}
+	// Pinning a second provider keeps the first one.
+	if err := SavePin(path, SeriesPin{Name: "One Piece", MangaDex: "uuid"}); err != nil {
+		t.Fatalf("SavePin error: %v", err)
+	}
+	pins, err := LoadPins(path)
+	if err != nil {
+		t.Fatalf("LoadPins error: %v", err)
+	}
+	want := []SeriesPin{{Name: "One Piece", Shikimori: 13, MangaDex: "uuid"}, {Pattern: "^Naruto", AniList: 2}}
+	if !reflect.DeepEqual(pins, want) {
+		t.Fatalf("pins = %+v, want %+v", pins, want)
+	}
+	if err := SavePin(path, SeriesPin{Name: "Bleach"}); err == nil {
+		t.Fatal("pin without IDs should be rejected")
+	}
+}
+
+func TestLookupMetadataPinned(t *testing.T) {
+	usePins(t, `[{"name": "Test Manga", "shikimori": 13}]`)
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `[]`
+		switch req.URL.Path {
+		case "/api/mangas/13/roles":
+		case "/api/mangas/13":
+			body = `{"id": 13, "name": "One Piece", "russian": "Ван-Пис", "url": "/mangas/13", "aired_on": "1997-07-22",
+			"genres": [{"name": "Action", "russian": "Экшен"}, {"name": "Comedy", "russian": "Комедия"}]}`
+		default:
+			t.Fatalf("pinned series should not be searched, got %s", req.URL.String())
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	}))
+
+	meta, err := LookupMetadata("Test_Manga")
+	if err != nil {
+		t.Fatalf("LookupMetadata error: %v", err)
+	}
+	if meta.Title != "Ван-Пис" || !reflect.DeepEqual(meta.Series.Genres, []string{"Action", "Comedy"}) || meta.Series.Year != 1997 {
+		t.Fatalf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestFetchMangaDexVolumesPinned(t *testing.T) {
+	usePins(t, `[{"name": "Test Manga", "mangadex": "pinned"}]`)
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/manga/pinned/aggregate" {
+			t.Fatalf("pinned series should not be searched, got %s", req.URL.String())
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"volumes": {"1": {"volume": "1", "chapters": {"1": {"chapter": "1"}}}}}`)),
+			Header:     make(http.Header),
+		}, nil
+	}))
+
+	got, err := FetchMangaDexVolumes("Test_Manga")
+	if err != nil {
+		t.Fatalf("FetchMangaDexVolumes error: %v", err)
+	}
+	if want := map[string][]string{"1": {"1"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FetchMangaDexVolumes = %v, want %v", got, want)
+	}
+}
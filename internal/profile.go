@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	SpreadKeep  = "keep"  // keep landscape pages whole and mark them DoublePage
+	SpreadSplit = "split" // replace a spread with its two halves
+	SpreadBoth  = "both"  // keep the spread and append its halves
+
+	DirectionRTL = "rtl" // manga
+	DirectionLTR = "ltr" // manhwa, western comics
+)
+
+// OutputProfile describes one output library. Every volume is written once per
+// profile into output/<Name>/.
+type OutputProfile struct {
+	Name             string             `json:"name"`
+	Format           string             `json:"format"`
+	Spreads          string             `json:"spreads"`
+	ReadingDirection string             `json:"reading_direction"`
+	LongStrip        LongStripSettings  `json:"long_strip"`
+	Recompress       RecompressSettings `json:"recompress"`
+	// Panels enables panel detection: a <book>.panels.json sidecar is written
+	// next to the output and EPUBs get Kindle region magnification.
+	Panels bool `json:"panels"`
+	// Parts splits oversized volumes into several files.
+	Parts PartSettings `json:"parts"`
+	// Naming overrides the global output templates for this library.
+	Naming NamingSettings `json:"naming"`
+}
+
+func DefaultProfile() OutputProfile {
+	return OutputProfile{
+		Name:             "cbz",
+		Format:           "cbz",
+		Spreads:          SpreadKeep,
+		ReadingDirection: DirectionRTL,
+		LongStrip: LongStripSettings{
+			Mode:         LongStripOff,
+			DeviceWidth:  1072,
+			DeviceHeight: 1448,
+			AutoAspect:   2.5,
+		},
+		Recompress: RecompressSettings{
+			Enabled:    false,
+			Quality:    85,
+			MinQuality: 40,
+		},
+	}
+}
+
+func (p *OutputProfile) applyDefaults() {
+	def := DefaultProfile()
+	if p.Format == "" {
+		p.Format = def.Format
+	}
+	if p.Name == "" {
+		p.Name = p.Format
+	}
+	if p.Spreads == "" {
+		p.Spreads = def.Spreads
+	}
+	if p.ReadingDirection == "" {
+		p.ReadingDirection = def.ReadingDirection
+	}
+	if p.LongStrip.Mode == "" {
+		p.LongStrip.Mode = def.LongStrip.Mode
+	}
+	if p.LongStrip.DeviceWidth == 0 && p.LongStrip.DeviceHeight == 0 {
+		p.LongStrip.DeviceWidth = def.LongStrip.DeviceWidth
+		p.LongStrip.DeviceHeight = def.LongStrip.DeviceHeight
+	}
+	if p.LongStrip.AutoAspect == 0 {
+		p.LongStrip.AutoAspect = def.LongStrip.AutoAspect
+	}
+	if p.Recompress.Quality == 0 {
+		p.Recompress.Quality = def.Recompress.Quality
+	}
+	if p.Recompress.MinQuality == 0 {
+		p.Recompress.MinQuality = def.Recompress.MinQuality
+	}
+}
+
+func (p OutputProfile) Validate() error {
+	if p.Name == "" {
+		return errors.New("не задано имя")
+	}
+	if p.Format != "cbz" && p.Format != "epub" {
+		return fmt.Errorf("неизвестный формат %q", p.Format)
+	}
+	switch p.Spreads {
+	case SpreadKeep, SpreadSplit, SpreadBoth:
+	default:
+		return fmt.Errorf("неизвестный режим разворотов %q", p.Spreads)
+	}
+	switch p.ReadingDirection {
+	case DirectionRTL, DirectionLTR:
+	default:
+		return fmt.Errorf("неизвестное направление чтения %q", p.ReadingDirection)
+	}
+	switch p.LongStrip.Mode {
+	case LongStripOff, LongStripOn, LongStripAuto:
+	default:
+		return fmt.Errorf("неизвестный ленточный режим %q", p.LongStrip.Mode)
+	}
+	if p.LongStrip.DeviceWidth <= 0 || p.LongStrip.DeviceHeight <= 0 {
+		return errors.New("long_strip: размеры устройства должны быть положительными")
+	}
+	rc := p.Recompress
+	if rc.MinQuality < 1 || rc.Quality > 100 || rc.MinQuality > rc.Quality {
+		return fmt.Errorf("recompress: нужно 1 <= min_quality <= quality <= 100, получено %d и %d", rc.MinQuality, rc.Quality)
+	}
+	if rc.MaxVolumeMB < 0 {
+		return errors.New("recompress: max_volume_mb не может быть отрицательным")
+	}
+	if p.Parts.MaxMB < 0 || p.Parts.MaxPages < 0 {
+		return errors.New("parts: ограничения не могут быть отрицательными")
+	}
+	if err := p.Naming.validate(true); err != nil {
+		return fmt.Errorf("naming: %w", err)
+	}
+	return nil
+}
+
+// profilePages applies the per-profile stages. Source files are never
+// modified: new images are written into dir.
+func profilePages(pages []*Page, profile OutputProfile, dir string) ([]*Page, error) {
+	pages, err := applyLongStrip(pages, profile.LongStrip, dir)
+	if err != nil {
+		return nil, fmt.Errorf("ленточный режим: %w", err)
+	}
+	pages, err = applySpreads(pages, profile, dir)
+	if err != nil {
+		return nil, fmt.Errorf("развороты: %w", err)
+	}
+	pages, err = applyRecompress(pages, profile.Recompress, dir)
+	if err != nil {
+		return nil, fmt.Errorf("перекодирование: %w", err)
+	}
+	if profile.Panels {
+		if err := detectPagePanels(pages, profile.ReadingDirection); err != nil {
+			return nil, fmt.Errorf("поиск кадров: %w", err)
+		}
+	}
+	return pages, nil
+}
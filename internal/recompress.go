@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type RecompressSettings struct {
+	Enabled bool `json:"enabled"`
+	// Quality is the JPEG quality used for PNG pages.
+	Quality int `json:"quality"`
+	// MaxVolumeMB enables the size budget: the quality is lowered, down to
+	// MinQuality, until the pages of a volume fit. 0 disables the budget.
+	MaxVolumeMB int `json:"max_volume_mb"`
+	MinQuality  int `json:"min_quality"`
+}
+
+// applyRecompress re-encodes PNG pages to JPEG. Pages that would grow are
+// left as they are.
+func applyRecompress(pages []*Page, cfg RecompressSettings, dir string) ([]*Page, error) {
+	if !cfg.Enabled {
+		return pages, nil
+	}
+
+	quality := cfg.Quality
+	if cfg.MaxVolumeMB > 0 {
+		var err error
+		quality, err = budgetQuality(pages, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clashes := jpegNameClashes(pages)
+	out := make([]*Page, len(pages))
+	converted := make([]bool, len(pages))
+	err := forEachPage(pages, func(i int, p *Page) error {
+		out[i] = p
+		if !isPNG(p.Name) || p.Problem != "" {
+			return nil
+		}
+		if clashes[p] {
+			log.Printf("⚠️ Страница %s остаётся PNG: имя %s уже занято", p.Name, jpegPageName(p.Name))
+			return nil
+		}
+		data, grows, err := transcodePage(p, quality)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		if grows {
+			return nil
+		}
+
+		page := *p
+		page.Name = jpegPageName(p.Name)
+		page.Path = filepath.Join(dir, filepath.FromSlash(page.Name))
+		if err := os.MkdirAll(filepath.Dir(page.Path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(page.Path, data, 0644); err != nil {
+			return err
+		}
+		out[i] = &page
+		converted[i] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for _, c := range converted {
+		if c {
+			count++
+		}
+	}
+	log.Printf("🗜 Перекодировано в JPEG (качество %d): %d страниц", quality, count)
+	return out, nil
+}
+
+// budgetQuality binary-searches the highest quality in
+// [MinQuality, Quality] at which the volume fits MaxVolumeMB. The pages are
+// decoded once; each step only re-encodes a sample of them.
+func budgetQuality(pages []*Page, cfg RecompressSettings) (int, error) {
+	budget := int64(cfg.MaxVolumeMB) << 20
+	sample, err := sampleVolume(pages)
+	if err != nil {
+		return 0, err
+	}
+
+	fits := func(q int) (bool, error) {
+		size, err := sample.sizeAt(q)
+		if err != nil {
+			return false, err
+		}
+		return size <= budget, nil
+	}
+
+	ok, err := fits(cfg.Quality)
+	if err != nil || ok {
+		return cfg.Quality, err
+	}
+
+	lo, hi := cfg.MinQuality, cfg.Quality-1
+	best := -1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			best = mid
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	if best < 0 {
+		log.Printf("⚠️ Том не укладывается в %d МБ даже при качестве %d", cfg.MaxVolumeMB, cfg.MinQuality)
+		return cfg.MinQuality, nil
+	}
+	log.Printf("📏 Качество JPEG %d укладывается в бюджет %d МБ", best, cfg.MaxVolumeMB)
+	return best, nil
+}
+
+// budgetSamplePages bounds how many PNG pages the size estimate decodes and
+// keeps in memory.
+const budgetSamplePages = 8
+
+// volumeSample estimates the size of a volume after transcoding from a few
+// evenly spread PNG pages, decoded once.
+type volumeSample struct {
+	// fixed is the size of the pages that are not transcoded.
+	fixed int64
+	// candidates is the source size of all PNG pages, sampled the size of
+	// those in images.
+	candidates, sampled int64
+	images              []image.Image
+	sources             []int64
+}
+
+func sampleVolume(pages []*Page) (*volumeSample, error) {
+	clashes := jpegNameClashes(pages)
+	sizes := make([]int64, len(pages))
+	if err := forEachPage(pages, func(i int, p *Page) error {
+		info, err := os.Stat(p.Path)
+		if err != nil {
+			return err
+		}
+		sizes[i] = info.Size()
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	s := &volumeSample{}
+	var candidates []*Page
+	var candidateSizes []int64
+	for i, p := range pages {
+		if isPNG(p.Name) && p.Problem == "" && !clashes[p] {
+			candidates = append(candidates, p)
+			candidateSizes = append(candidateSizes, sizes[i])
+			s.candidates += sizes[i]
+		} else {
+			s.fixed += sizes[i]
+		}
+	}
+
+	var picked []*Page
+	for i := 0; i < min(len(candidates), budgetSamplePages); i++ {
+		j := i * len(candidates) / min(len(candidates), budgetSamplePages)
+		picked = append(picked, candidates[j])
+		s.sources = append(s.sources, candidateSizes[j])
+		s.sampled += candidateSizes[j]
+	}
+	s.images = make([]image.Image, len(picked))
+	err := forEachPage(picked, func(i int, p *Page) error {
+		img, _, err := decodeImage(p.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.Name, err)
+		}
+		s.images[i] = flattenAlpha(img)
+		return nil
+	})
+	return s, err
+}
+
+// sizeAt estimates the volume size at a JPEG quality. Sampled pages that
+// would grow count with their source size, as applyRecompress keeps them.
+func (s *volumeSample) sizeAt(quality int) (int64, error) {
+	if s.sampled == 0 {
+		return s.fixed + s.candidates, nil
+	}
+	var encoded int64
+	for i, img := range s.images {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return 0, err
+		}
+		encoded += min(int64(buf.Len()), s.sources[i])
+	}
+	return s.fixed + encoded*s.candidates/s.sampled, nil
+}
+
+// volumeSizeAt estimates the size of the volume's pages after transcoding at
+// the given quality.
+func volumeSizeAt(pages []*Page, quality int) (int64, error) {
+	sample, err := sampleVolume(pages)
+	if err != nil {
+		return 0, err
+	}
+	return sample.sizeAt(quality)
+}
+
+// transcodePage encodes a page to JPEG in memory and reports whether the
+// result is not smaller than the source file.
+func transcodePage(p *Page, quality int) ([]byte, bool, error) {
+	img, _, err := decodeImage(p.Path)
+	if err != nil {
+		return nil, false, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flattenAlpha(img), &jpeg.Options{Quality: quality}); err != nil {
+		return nil, false, err
+	}
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), int64(buf.Len()) >= info.Size(), nil
+}
+
+// flattenAlpha composes a transparent page onto white; JPEG has no alpha and
+// would turn transparent areas black.
+func flattenAlpha(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); !ok || o.Opaque() {
+		return img
+	}
+	b := img.Bounds()
+	flat := image.NewRGBA(b)
+	draw.Draw(flat, b, image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, b, img, b.Min, draw.Over)
+	return flat
+}
+
+// jpegNameClashes returns the PNG pages whose JPEG name is already taken by
+// another page ("010.png" next to "010.jpg"). They stay PNG rather than
+// producing two archive entries with the same name.
+func jpegNameClashes(pages []*Page) map[*Page]bool {
+	names := map[string]bool{}
+	for _, p := range pages {
+		names[strings.ToLower(p.Name)] = true
+	}
+	clashes := map[*Page]bool{}
+	for _, p := range pages {
+		if isPNG(p.Name) && names[strings.ToLower(jpegPageName(p.Name))] {
+			clashes[p] = true
+		}
+	}
+	return clashes
+}
+
+func jpegPageName(name string) string {
+	return strings.TrimSuffix(name, path.Ext(name)) + ".jpg"
+}
+
+func isPNG(name string) bool {
+	return strings.ToLower(path.Ext(name)) == ".png"
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func noisePage(t *testing.T, path string, size int) *Page {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(256))
+	}
+	writeImageFile(t, path, img)
+	return &Page{Path: path, Name: filepath.Base(path), Width: size, Height: size}
+}
+
+func flatPage(t *testing.T, path string) *Page {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	writeImageFile(t, path, img)
+	return &Page{Path: path, Name: filepath.Base(path), Width: 8, Height: 8}
+}
+
+func TestApplyRecompress(t *testing.T) {
+	src := t.TempDir()
+	noisy := noisePage(t, filepath.Join(src, "001.png"), 200)
+	flat := flatPage(t, filepath.Join(src, "002.png"))
+
+	cfg := RecompressSettings{Enabled: true, Quality: 80, MinQuality: 40}
+	got, err := applyRecompress([]*Page{noisy, flat}, cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("applyRecompress error: %v", err)
+	}
+
+	if got[0].Name != "001.jpg" {
+		t.Fatalf("noisy page name = %s, want 001.jpg", got[0].Name)
+	}
+	if _, _, err := decodeImage(got[0].Path); err != nil {
+		t.Fatalf("decode transcoded page: %v", err)
+	}
+	// A tiny flat PNG grows as JPEG and must be kept.
+	if got[1] != flat {
+		t.Fatalf("flat page should be kept, got %+v", got[1])
+	}
+	if _, err := os.Stat(noisy.Path); err != nil {
+		t.Fatalf("source page must not be removed: %v", err)
+	}
+
+	// 010.png cannot become 010.jpg while another page has that name.
+	taken := noisePage(t, filepath.Join(src, "010.jpg"), 50)
+	clash := noisePage(t, filepath.Join(src, "010.png"), 200)
+	got, err = applyRecompress([]*Page{taken, clash}, cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("applyRecompress error: %v", err)
+	}
+	if got[0] != taken || got[1] != clash {
+		t.Fatalf("clashing page should stay PNG, got %s and %s", got[0].Name, got[1].Name)
+	}
+}
+
+func TestFlattenAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	flat := flattenAlpha(img)
+	if r, g, b, _ := flat.At(1, 1).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+		t.Fatalf("transparent pixel = %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+	if r, g, _, _ := flat.At(0, 0).RGBA(); r>>8 != 255 || g>>8 != 0 {
+		t.Fatal("opaque pixel changed")
+	}
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	if flattenAlpha(gray) != image.Image(gray) {
+		t.Fatal("opaque page should be passed through")
+	}
+}
+
+func TestBudgetQuality(t *testing.T) {
+	src := t.TempDir()
+	page := noisePage(t, filepath.Join(src, "001.png"), 1024)
+
+	high, err := volumeSizeAt([]*Page{page}, 95)
+	if err != nil {
+		t.Fatalf("volumeSizeAt error: %v", err)
+	}
+	if high <= 1<<20 {
+		t.Skipf("noise page too small for a 1 MB budget: %d bytes", high)
+	}
+
+	cfg := RecompressSettings{Enabled: true, Quality: 95, MinQuality: 10, MaxVolumeMB: 1}
+	q, err := budgetQuality([]*Page{page}, cfg)
+	if err != nil {
+		t.Fatalf("budgetQuality error: %v", err)
+	}
+	if q >= 95 || q < 10 {
+		t.Fatalf("quality = %d, want a value in [10, 95)", q)
+	}
+	size, err := volumeSizeAt([]*Page{page}, q)
+	if err != nil {
+		t.Fatalf("volumeSizeAt error: %v", err)
+	}
+	if size > 1<<20 {
+		t.Fatalf("volume at quality %d is %d bytes, over budget", q, size)
+	}
+}
+
+func TestVolumeSizeAtSample(t *testing.T) {
+	src := t.TempDir()
+	var pages []*Page
+	for i := range 3 * budgetSamplePages {
+		pages = append(pages, noisePage(t, filepath.Join(src, fmt.Sprintf("%03d.png", i)), 64))
+	}
+	one, err := volumeSizeAt(pages[:1], 80)
+	if err != nil {
+		t.Fatalf("volumeSizeAt error: %v", err)
+	}
+	// Identical pages: the sample scales to the exact total.
+	all, err := volumeSizeAt(pages, 80)
+	if err != nil {
+		t.Fatalf("volumeSizeAt error: %v", err)
+	}
+	if all != one*int64(len(pages)) {
+		t.Fatalf("volumeSizeAt = %d, want %d", all, one*int64(len(pages)))
+	}
+}
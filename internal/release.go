@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Release is the scanlation information carried by archive and folder names
+// such as "[GroupName] Title v03 (Digital) [1080p]".
+type Release struct {
+	Group string
+	Tags  []string
+}
+
+// leadingGroup matches the scanlation group, which by convention comes first
+// in square brackets.
+var leadingGroup = regexp.MustCompile(`^\s*[\[【]([^\]】]+)[\]】]`)
+
+// parseRelease collects the group and tags of several names, e.g. the
+// archive and the manga folder. The first group found wins and repeated tags
+// are kept once.
+func parseRelease(names ...string) Release {
+	var r Release
+	for _, name := range names {
+		group := ""
+		if m := leadingGroup.FindStringSubmatch(normalizeWidth(name)); m != nil {
+			group = strings.TrimSpace(m[1])
+		}
+		if r.Group == "" {
+			r.Group = group
+		}
+		for _, tag := range ParseName(name).Tags {
+			if tag != group {
+				r.Tags = mergeTags(r.Tags, tag)
+			}
+		}
+	}
+	return r
+}
+
+// mergeTags appends the tags missing from list, ignoring case.
+func mergeTags(list []string, tags ...string) []string {
+	for _, tag := range tags {
+		found := false
+		for _, have := range list {
+			if strings.EqualFold(have, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, tag)
+		}
+	}
+	return list
+}
+
+// seriesName strips bracketed release tags and group prefixes from a manga
+// folder name, leaving the title to search providers with. Volume, chapter
+// and special markers are left alone: "Extra Life" is a title, not an extra.
+func seriesName(folder string) string {
+	s := nameTag.ReplaceAllString(normalizeWidth(folder), " ")
+	if title := strings.Trim(nameSpaces.ReplaceAllString(s, " "), " -–—,"); title != "" {
+		return title
+	}
+	return folder
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRelease(t *testing.T) {
+	got := parseRelease("[GroupName] Title v03 (Digital) [1080p]", "Title (digital) (2019)")
+	want := Release{Group: "GroupName", Tags: []string{"Digital", "1080p", "2019"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseRelease = %+v, want %+v", got, want)
+	}
+
+	if got := parseRelease("Title (Digital)"); got.Group != "" {
+		t.Fatalf("a trailing tag is not a group: %+v", got)
+	}
+
+	for folder, want := range map[string]string{
+		"[GroupName] Test_Manga (Digital)": "Test Manga",
+		"Extra Life":                       "Extra Life",
+		"Special A [Team]":                 "Special A",
+		"SP Mission":                       "SP Mission",
+		"Bonus_Track":                      "Bonus Track",
+		"【Group】 Vol Chapter":              "Vol Chapter",
+	} {
+		if got := seriesName(folder); got != want {
+			t.Fatalf("seriesName(%q) = %q, want %q", folder, got, want)
+		}
+	}
+}
+
+func TestComicInfoRelease(t *testing.T) {
+	meta := &Metadata{Title: "Test", Group: "GroupName", ReleaseTags: []string{"Digital", "1080p"}}
+	data, err := buildComicInfo(nil, meta)
+	if err != nil {
+		t.Fatalf("buildComicInfo error: %v", err)
+	}
+	for _, want := range []string{
+		"<Translator>GroupName</Translator>",
+		"<ScanInformation>GroupName</ScanInformation>",
+		"<Notes>Теги релиза: Digital, 1080p</Notes>",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("ComicInfo.xml missing %s, got %s", want, data)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobReport summarises the processing of one input archive. It is written to
+// output/reports/<archive>.json.
+type JobReport struct {
+	Archive  string          `json:"archive"`
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	Volumes  []*VolumeReport `json:"volumes"`
+}
+
+type VolumeReport struct {
+	Name     string    `json:"name"`
+	Outputs  []string  `json:"outputs,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+	Error    string    `json:"error,omitempty"`
+
+	// mu guards Findings: page stages run on a worker pool.
+	mu sync.Mutex
+}
+
+// Finding is a problem with a single page and what was done about it.
+type Finding struct {
+	Page    string `json:"page"`
+	Problem string `json:"problem"`
+	Detail  string `json:"detail,omitempty"`
+	Action  string `json:"action"`
+}
+
+func NewJobReport(archive string) *JobReport {
+	return &JobReport{Archive: archive, Started: time.Now()}
+}
+
+func (r *JobReport) Volume(name string) *VolumeReport {
+	v := &VolumeReport{Name: name}
+	r.Volumes = append(r.Volumes, v)
+	return v
+}
+
+// AddFinding is safe to call on a nil report and from several goroutines.
+func (v *VolumeReport) AddFinding(f Finding) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	v.Findings = append(v.Findings, f)
+	v.mu.Unlock()
+}
+
+func (r *JobReport) Save(dir string) (string, error) {
+	r.Finished = time.Now()
+	for _, v := range r.Volumes {
+		// Findings of parallel stages arrive in completion order; a stable
+		// natural sort restores page order ("page2" before "page10") and
+		// keeps the stage order for each page.
+		sort.SliceStable(v.Findings, func(i, j int) bool {
+			return compareNatural(v.Findings[i].Page, v.Findings[j].Page) < 0
+		})
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, strings.TrimSuffix(r.Archive, ".zip")+".json")
+	return out, os.WriteFile(out, data, 0644)
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestJobReportSaveSortsFindings(t *testing.T) {
+	report := NewJobReport("test.zip")
+	volume := report.Volume("Том 1")
+	for _, page := range []string{"page10.jpg", "page2.jpg", "page1.jpg", "page2.jpg"} {
+		volume.AddFinding(Finding{Page: page, Problem: page})
+	}
+	volume.Findings[3].Problem = "second"
+
+	out, err := report.Save(t.TempDir())
+	if err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var saved JobReport
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	var got []string
+	for _, f := range saved.Volumes[0].Findings {
+		got = append(got, f.Page+":"+f.Problem)
+	}
+	want := []string{"page1.jpg:page1.jpg", "page2.jpg:page2.jpg", "page2.jpg:second", "page10.jpg:page10.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findings = %v, want %v", got, want)
+	}
+}
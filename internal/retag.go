@@ -0,0 +1,306 @@
+package internal
+
+import (
+	"archive/zip"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RetagResult is what retag did, or would do, to one archive.
+type RetagResult struct {
+	Path string
+	// Diff lists the removed ("- ") and added ("+ ") ComicInfo.xml lines;
+	// empty when the archive is already up to date.
+	Diff  []string
+	Error string
+}
+
+var (
+	shikimoriMangaURL = regexp.MustCompile(`shikimori\.(?:one|me)/mangas/[a-z]*(\d+)`)
+	aniListMangaURL   = regexp.MustCompile(`anilist\.co/manga/(\d+)`)
+)
+
+// RetagLibrary refetches the metadata of every series under root and
+// rewrites ComicInfo.xml in its CBZs. Images are copied without
+// recompression; with dryRun nothing is written.
+func RetagLibrary(root string, dryRun bool) ([]RetagResult, error) {
+	folders, err := cbzFolders(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("в %s нет архивов CBZ", root)
+	}
+
+	var results []RetagResult
+	for _, dir := range slices.Sorted(maps.Keys(folders)) {
+		files := folders[dir]
+		log.Printf("🏷 Серия %s: архивов — %d", dir, len(files))
+		meta, err := identifySeries(dir, files)
+		if err != nil {
+			log.Printf("⚠️ Метаданные серии %s не получены: %v", dir, err)
+			for _, path := range files {
+				results = append(results, RetagResult{Path: path, Error: err.Error()})
+			}
+			continue
+		}
+		for _, path := range files {
+			diff, err := retagCBZ(path, meta, dryRun)
+			result := RetagResult{Path: path, Diff: diff}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// cbzFolders groups the CBZs under root by folder.
+func cbzFolders(root string) (map[string][]string, error) {
+	folders := map[string][]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".cbz") {
+			dir := filepath.Dir(path)
+			folders[dir] = append(folders[dir], path)
+		}
+		return nil
+	})
+	return folders, err
+}
+
+// identifySeries finds the metadata of a series folder: a pin on the folder
+// name or on the source folder that starts the file names ("One_Piece__Vol_01")
+// wins, then the provider page recorded in ComicInfo Web, then a search by
+// the folder name.
+func identifySeries(dir string, files []string) (*Metadata, error) {
+	folder := filepath.Base(dir)
+	names := []string{folder}
+	for _, path := range files {
+		base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if source, _, ok := strings.Cut(base, "__"); ok {
+			names = mergeTags(names, source)
+		}
+	}
+	for _, name := range names {
+		if findPin(name) != nil {
+			return LookupMetadata(name)
+		}
+	}
+
+	if info, _, err := readComicInfo(files[0]); err == nil {
+		if m := shikimoriMangaURL.FindStringSubmatch(info.Web); m != nil {
+			id, _ := strconv.Atoi(m[1])
+			return FetchShikimoriByID(id, folder)
+		}
+		if m := aniListMangaURL.FindStringSubmatch(info.Web); m != nil {
+			id, _ := strconv.Atoi(m[1])
+			return FetchAniListByID(id, folder)
+		}
+	}
+	return LookupMetadata(folder)
+}
+
+// readComicInfo returns the parsed and the raw ComicInfo.xml of a CBZ; raw
+// is nil when the archive has none.
+func readComicInfo(path string) (comicInfo, []byte, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return comicInfo{}, nil, err
+	}
+	defer r.Close()
+	return comicInfoOf(r.File)
+}
+
+func comicInfoOf(files []*zip.File) (comicInfo, []byte, error) {
+	var info comicInfo
+	for _, f := range files {
+		if f.Name != "ComicInfo.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return info, nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return info, nil, err
+		}
+		if err := xml.Unmarshal(data, &info); err != nil {
+			return info, nil, fmt.Errorf("разбор ComicInfo.xml: %w", err)
+		}
+		return info, data, nil
+	}
+	return info, nil, nil
+}
+
+// retagCBZ rebuilds ComicInfo.xml from the series metadata and what the old
+// one says about the book itself: volume, number, group, pages. Elements and
+// page attributes the converter does not write are carried over.
+func retagCBZ(path string, meta *Metadata, dryRun bool) ([]string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	old, oldXML, err := comicInfoOf(r.File)
+	if err != nil {
+		return nil, err
+	}
+	info := newComicInfo(retagMetadata(old, meta, filepath.Base(filepath.Dir(path))))
+	info.Pages, info.Extra = old.Pages, old.Extra
+	newXML, err := xml.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffLines(string(oldXML), string(newXML))
+	if len(diff) == 0 || dryRun {
+		return diff, nil
+	}
+
+	tmp, err := rewriteCBZ(path, r.File, newXML)
+	if err != nil {
+		return nil, err
+	}
+	r.Close()
+	// CreateTemp makes the file 0600; readers running as another user need
+	// the permissions of the original.
+	if info, err := os.Stat(path); err == nil {
+		if err := os.Chmod(tmp, info.Mode().Perm()); err != nil {
+			os.Remove(tmp)
+			return nil, err
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	log.Printf("🏷 Обновлён ComicInfo.xml: %s", path)
+	return diff, nil
+}
+
+// retagMetadata combines fresh series metadata with the book fields of an
+// old ComicInfo. A title such as "Old — Том 1" keeps its book part.
+func retagMetadata(old comicInfo, meta *Metadata, folder string) *Metadata {
+	book := *meta
+	book.Volume, book.Number = old.Volume, old.Number
+	book.Group = old.Translator
+	book.ReleaseTags = nil
+	if tags, ok := strings.CutPrefix(old.Notes, releaseTagsNote); ok {
+		book.ReleaseTags = strings.Split(tags, ", ")
+	}
+	if old.LanguageISO != "" {
+		book.Language = old.LanguageISO
+	}
+
+	oldSeries := cmp.Or(old.Series, folder)
+	switch {
+	case old.Title == "" || old.Title == oldSeries:
+		book.Title = meta.Series.Title
+	case strings.HasPrefix(old.Title, oldSeries+" — "):
+		book.Title = meta.Series.Title + strings.TrimPrefix(old.Title, oldSeries)
+	default:
+		book.Title = old.Title
+	}
+	return &book
+}
+
+// rewriteCBZ writes a copy of the archive with a new ComicInfo.xml next to
+// it and returns its path. Entries are copied still compressed.
+func rewriteCBZ(path string, files []*zip.File, comicInfoXML []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".retag-*.cbz")
+	if err != nil {
+		return "", err
+	}
+	fail := func(err error) (string, error) {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	w := zip.NewWriter(tmp)
+	for _, f := range files {
+		if f.Name == "ComicInfo.xml" {
+			continue
+		}
+		if err := w.Copy(f); err != nil {
+			return fail(fmt.Errorf("%s: %w", f.Name, err))
+		}
+	}
+	writer, err := w.Create("ComicInfo.xml")
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := writer.Write(comicInfoXML); err != nil {
+		return fail(err)
+	}
+	if err := w.Close(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// diffLines lists the lines removed from a ("- ") and added in b ("+ ")
+// along their longest common subsequence.
+func diffLines(a, b string) []string {
+	x, y := splitLines(a), splitLines(b)
+	// lcs[i][j] is the length of the common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "- "+x[i])
+			i++
+		default:
+			diff = append(diff, "+ "+y[j])
+			j++
+		}
+	}
+	return diff
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"archive/zip"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRetagLibrary(t *testing.T) {
+	usePins(t, `[]`)
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `[]`
+		switch req.URL.Path {
+		case "/api/mangas/13":
+			body = `{"id": 13, "name": "One Piece", "russian": "Ван-Пис", "url": "/mangas/13", "aired_on": "1997-07-22"}`
+		case "/api/mangas/13/roles":
+			body = `[{"roles": ["Story & Art"], "person": {"name": "Eiichiro Oda"}}]`
+		default:
+			t.Fatalf("series with a Shikimori page should not be searched, got %s", req.URL.String())
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	}))
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "Old Title")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	page := filepath.Join(t.TempDir(), "001.jpg")
+	writeJPEG(t, page, 20, 30)
+	cbz := filepath.Join(dir, "One_Piece__Vol_01.cbz")
+	meta := &Metadata{
+		Title: "Old Title — Том 1", Volume: "1", Number: "1-8", Group: "Team", ReleaseTags: []string{"Digital"},
+		Series: Series{Title: "Old Title", URL: "https://shikimori.one/mangas/13"},
+	}
+	if err := CreateCBZ([]*Page{{Path: page, Name: "001.jpg", Width: 20, Height: 30}}, meta, cbz); err != nil {
+		t.Fatalf("CreateCBZ error: %v", err)
+	}
+	// Elements and attributes the converter does not write must survive.
+	r, err := zip.OpenReader(cbz)
+	if err != nil {
+		t.Fatalf("open cbz: %v", err)
+	}
+	_, oldXML, err := comicInfoOf(r.File)
+	if err != nil {
+		t.Fatalf("read ComicInfo.xml: %v", err)
+	}
+	edited := strings.Replace(string(oldXML), "  <Pages>", "  <StoryArc>East Blue</StoryArc>\n  <Manga>YesAndRightToLeft</Manga>\n  <Pages>", 1)
+	edited = strings.Replace(edited, `<Page Image="0"`, `<Page Image="0" Key="k1"`, 1)
+	tmp, err := rewriteCBZ(cbz, r.File, []byte(edited))
+	r.Close()
+	if err != nil {
+		t.Fatalf("rewriteCBZ error: %v", err)
+	}
+	if err := os.Rename(tmp, cbz); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := os.Chmod(cbz, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	before, err := os.ReadFile(cbz)
+	if err != nil {
+		t.Fatalf("read cbz: %v", err)
+	}
+
+	results, err := RetagLibrary(root, true)
+	if err != nil {
+		t.Fatalf("RetagLibrary error: %v", err)
+	}
+	if len(results) != 1 || results[0].Error != "" || len(results[0].Diff) == 0 {
+		t.Fatalf("dry run should report a diff, got %+v", results)
+	}
+	if !slices.Contains(results[0].Diff, "-   <Title>Old Title — Том 1</Title>") || !slices.Contains(results[0].Diff, "+   <Title>Ван-Пис — Том 1</Title>") {
+		t.Fatalf("unexpected diff: %v", results[0].Diff)
+	}
+	if after, _ := os.ReadFile(cbz); string(after) != string(before) {
+		t.Fatal("dry run must not touch the archive")
+	}
+
+	if _, err := RetagLibrary(root, false); err != nil {
+		t.Fatalf("RetagLibrary error: %v", err)
+	}
+	if info, err := os.Stat(cbz); err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("retagged archive should keep its permissions, got %v %v", info.Mode(), err)
+	}
+	files, contents := readZipEntries(t, cbz)
+	if len(files) != 2 || files[0].Name != "001.jpg" {
+		t.Fatalf("unexpected entries: %v", files)
+	}
+	image, _ := os.ReadFile(page)
+	if contents["001.jpg"] != string(image) {
+		t.Fatal("image entry changed")
+	}
+	info := contents["ComicInfo.xml"]
+	for _, want := range []string{
+		"<Title>Ван-Пис — Том 1</Title>", "<Series>Ван-Пис</Series>", "<Number>1-8</Number>", "<Volume>1</Volume>",
+		"<Writer>Eiichiro Oda</Writer>", "<Translator>Team</Translator>", "<Notes>Теги релиза: Digital</Notes>",
+		`<Page Image="0" ImageWidth="20" ImageHeight="30" Key="k1">`,
+		"<StoryArc>East Blue</StoryArc>", "<Manga>YesAndRightToLeft</Manga>",
+	} {
+		if !strings.Contains(info, want) {
+			t.Fatalf("ComicInfo.xml missing %s, got %s", want, info)
+		}
+	}
+
+	results, err = RetagLibrary(root, false)
+	if err != nil || len(results) != 1 || len(results[0].Diff) != 0 {
+		t.Fatalf("second run should find nothing to change, got %+v, %v", results, err)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("a\nb\nc\n", "a\nB\nc\nd")
+	if want := []string{"- b", "+ B", "+ d"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffLines = %v, want %v", got, want)
+	}
+}
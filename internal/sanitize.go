@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	CharsetUnicode = "unicode" // keep names as they are
+	CharsetASCII   = "ascii"   // transliterate for devices with poor Unicode support
+)
+
+// maxNameBytes keeps a name with suffixes such as ".panels.json" within the
+// 255-byte limit of common filesystems.
+const maxNameBytes = 240
+
+// SafeName makes a file name out of name: spaces become underscores and the
+// result is sanitized for charset CharsetUnicode.
+func SafeName(name string) string {
+	return sanitizeName(strings.ReplaceAll(name, " ", "_"), CharsetUnicode)
+}
+
+// sanitizeName makes a single path component that is valid on Linux, macOS
+// and Windows: NFC-normalized, without reserved and control characters,
+// trailing dots and spaces or Windows device names, and at most maxNameBytes
+// long.
+func sanitizeName(name string, charset string) string {
+	name = norm.NFC.String(name)
+	if charset == CharsetASCII {
+		name = transliterate(name)
+	}
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.TrimRight(truncateBytes(strings.TrimRight(name, ". "), maxNameBytes), ". ")
+	if name == "" {
+		return "_"
+	}
+	base, ext, _ := strings.Cut(name, ".")
+	if isWindowsReserved(base) {
+		name = base + "_"
+		if ext != "" {
+			name += "." + ext
+		}
+	}
+	return name
+}
+
+// sanitizePath sanitizes every component of a slash-separated path. Empty
+// components are dropped.
+func sanitizePath(p string, charset string) []string {
+	var parts []string
+	for _, part := range strings.Split(p, "/") {
+		if part != "" {
+			parts = append(parts, sanitizeName(part, charset))
+		}
+	}
+	return parts
+}
+
+func truncateBytes(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	s = s[:limit]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func isWindowsReserved(base string) bool {
+	switch strings.ToUpper(base) {
+	case "CON", "PRN", "AUX", "NUL",
+		"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+		"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9":
+		return true
+	}
+	return false
+}
+
+var cyrillicLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "",
+	'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u",
+}
+
+// hiraganaRomaji uses Hepburn romanization. Katakana is mapped to hiragana
+// before the lookup.
+var hiraganaRomaji = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o", 'ゔ': "vu",
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo", 'ゎ': "wa",
+}
+
+// transliterate converts Cyrillic and kana to Latin letters and strips
+// diacritics. Characters without an ASCII form, such as kanji, become "_".
+func transliterate(s string) string {
+	t, _ := transliterateLossy(s)
+	return t
+}
+
+// asciiName transliterates the first of names that has an ASCII form for
+// every character, so "鬼滅の刃" gives way to its romaji title instead of
+// "__no_". When none has, the first name is used with a hash of it appended,
+// keeping series that differ only in kanji apart.
+func asciiName(names ...string) string {
+	if len(names) == 0 || names[0] == "" {
+		return ""
+	}
+	for _, name := range names {
+		if t, lost := transliterateLossy(name); !lost && name != "" {
+			return t
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(names[0]))
+	return fmt.Sprintf("%s_%08x", transliterate(names[0]), h.Sum32())
+}
+
+// transliterateLossy is transliterate that also reports whether a
+// character had to be replaced with "_".
+func transliterateLossy(s string) (string, bool) {
+	lost := false
+	runes := []rune(s)
+	var b strings.Builder
+	double := false // a small tsu doubles the next consonant
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+			continue
+		}
+
+		lower := unicode.ToLower(r)
+		if t, ok := cyrillicLatin[lower]; ok {
+			if lower != r && t != "" {
+				t = strings.ToUpper(t[:1]) + t[1:]
+			}
+			b.WriteString(t)
+			continue
+		}
+
+		kana := toHiragana(r)
+		switch {
+		case kana == 'っ':
+			double = true
+			continue
+		case r == 'ー':
+			// The long vowel mark is dropped, as in "ramen".
+			continue
+		case r == '・':
+			b.WriteByte(' ')
+			continue
+		}
+		if t, ok := hiraganaRomaji[kana]; ok {
+			if i+1 < len(runes) {
+				if small, ok := smallY(toHiragana(runes[i+1])); ok && strings.HasSuffix(t, "i") && len(t) > 1 {
+					t = yoon(t, small)
+					i++
+				}
+			}
+			if double {
+				if strings.HasPrefix(t, "ch") {
+					t = "t" + t
+				} else if t[0] != 'a' && t[0] != 'i' && t[0] != 'u' && t[0] != 'e' && t[0] != 'o' && t[0] != 'n' {
+					t = t[:1] + t
+				}
+				double = false
+			}
+			b.WriteString(t)
+			continue
+		}
+
+		// Latin letters with diacritics and full-width forms.
+		ascii := ""
+		for _, d := range norm.NFKD.String(string(r)) {
+			if d < utf8.RuneSelf {
+				ascii += string(d)
+			}
+		}
+		if ascii == "" {
+			ascii, lost = "_", true
+		}
+		b.WriteString(ascii)
+	}
+	return b.String(), lost
+}
+
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+func smallY(r rune) (string, bool) {
+	switch r {
+	case 'ゃ':
+		return "a", true
+	case 'ゅ':
+		return "u", true
+	case 'ょ':
+		return "o", true
+	}
+	return "", false
+}
+
+// yoon combines an i-syllable with a small ya/yu/yo: "ki"+"a" is "kya",
+// "shi"+"a" is "sha".
+func yoon(syllable, vowel string) string {
+	stem := strings.TrimSuffix(syllable, "i")
+	switch stem {
+	case "sh", "ch", "j":
+		return stem + vowel
+	}
+	return stem + "y" + vowel
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"Fate/Zero: Вступление?", "Fate_Zero_ Вступление_"},
+		{`a<b>c"d|e*f\g`, "a_b_c_d_e_f_g"},
+		{"Title...", "Title"},
+		{"Title. ", "Title"},
+		{"..", "_"},
+		{"", "_"},
+		{"CON", "CON_"},
+		{"nul.txt", "nul_.txt"},
+		{"COM10", "COM10"},
+		{"tab\there", "tabhere"},
+		// "й" in NFD: и + combining breve.
+		{"Геро\u0438\u0306", "Герой"},
+	}
+	for _, tc := range cases {
+		if got := sanitizeName(tc.in, CharsetUnicode); got != tc.want {
+			t.Fatalf("sanitizeName(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	long := sanitizeName(strings.Repeat("я", 200), CharsetUnicode)
+	if len(long) > maxNameBytes || !strings.HasPrefix(long, "яяя") || strings.ContainsRune(long, '�') {
+		t.Fatalf("long name truncated to %d bytes: %q", len(long), long)
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"Щит Героя", "Shchit Geroya"},
+		{"Ёлка", "Yolka"},
+		{"ワンピース", "wanpisu"},
+		{"しょうねん", "shounen"},
+		{"きゃっちゃ", "kyatcha"},
+		{"がっこう", "gakkou"},
+		{"Pokémon", "Pokemon"},
+		{"ＡＢＣ", "ABC"},
+		{"進撃", "__"},
+	}
+	for _, tc := range cases {
+		if got := transliterate(tc.in); got != tc.want {
+			t.Fatalf("transliterate(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	if got := sanitizeName("Атака титанов: Финал", CharsetASCII); got != "Ataka titanov_ Final" {
+		t.Fatalf("ascii sanitizeName = %q", got)
+	}
+}
+
+func TestOutputPathSanitized(t *testing.T) {
+	profile := DefaultProfile()
+	profile.Naming.Charset = CharsetASCII
+	meta := &Metadata{Volume: "1", Series: Series{Title: "Fate/Zero: Начало"}}
+	dir, file, err := outputPath(profile, meta, bookName{Folder: "Fate Zero", Label: "Vol_01"}, "")
+	if err != nil {
+		t.Fatalf("outputPath error: %v", err)
+	}
+	if dir != filepath.Join("output", "cbz", "Fate_Zero_ Nachalo") || file != "Fate_Zero__Vol_01" {
+		t.Fatalf("outputPath = %q, %q", dir, file)
+	}
+
+	// Kanji titles fall back to the romaji title instead of "__no_".
+	meta.Series = Series{Title: "鬼滅の刃", Titles: Titles{Native: "鬼滅の刃", Romaji: "Kimetsu no Yaiba"}}
+	dir, file, err = outputPath(profile, meta, bookName{Folder: "鬼滅の刃", Label: "Vol_01"}, "")
+	if err != nil {
+		t.Fatalf("outputPath error: %v", err)
+	}
+	if dir != filepath.Join("output", "cbz", "Kimetsu no Yaiba") || file != "Kimetsu_no_Yaiba__Vol_01" {
+		t.Fatalf("outputPath = %q, %q", dir, file)
+	}
+}
+
+func TestASCIIName(t *testing.T) {
+	if got := asciiName("進撃の巨人", "Shingeki no Kyojin"); got != "Shingeki no Kyojin" {
+		t.Fatalf("asciiName = %q", got)
+	}
+	a, b := asciiName("鬼滅の刃"), asciiName("呪術の刃")
+	if a == b || !strings.HasPrefix(a, "__no__") {
+		t.Fatalf("kanji-only titles must stay apart: %q, %q", a, b)
+	}
+	if got := asciiName(""); got != "" {
+		t.Fatalf("asciiName of an empty name = %q", got)
+	}
+}
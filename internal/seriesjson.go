@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	seriesJSONName     = "series.json"
+	seriesJSONSnapshot = ".series.generated.json"
+	seriesJSONVersion  = "1.0.2"
+)
+
+// mylarAgeRatings maps ComicInfo age ratings to the Mylar series.json enum.
+var mylarAgeRatings = map[string]string{
+	AgeRatingEveryone: "All",
+	"Early Childhood": "All",
+	"Everyone 10+":    "9+",
+	"Teen":            "12+",
+	"Mature 17+":      "17+",
+	"M":               "17+",
+	AgeRatingAdult:    "Adult",
+	"X18+":            "Adult",
+	"R18+":            "Adult",
+}
+
+// seriesJSONFields maps the series metadata to Mylar series.json fields as
+// Komga and Kavita read them. Optional values are left out when unknown so
+// they never replace what is already in the file; total_issues,
+// publication_run and status are always written because the Mylar schema,
+// which Komga follows, requires them: 0, "" and "Continuing" when unknown.
+func seriesJSONFields(s Series) (map[string]any, error) {
+	fields := map[string]any{
+		"type":            "comicSeries",
+		"name":            s.Title,
+		"booktype":        "GN",
+		"total_issues":    s.VolumeCount,
+		"publication_run": publicationRun(s),
+		"status":          "Continuing",
+	}
+	if len(s.Publishers) > 0 {
+		fields["publisher"] = strings.Join(s.Publishers, ", ")
+	}
+	if s.Year > 0 {
+		fields["year"] = s.Year
+	}
+	if s.Description != "" {
+		fields["description_text"] = s.Description
+	}
+	if rating, ok := mylarAgeRatings[s.AgeRating]; ok {
+		fields["age_rating"] = rating
+	}
+	if cover := s.Cover(); cover != "" {
+		fields["ComicImage"] = cover
+	}
+	if s.Status == StatusCompleted || s.Status == StatusCancelled {
+		fields["status"] = "Ended"
+	}
+
+	// Round-trip so values compare equal to what is read back from disk.
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// publicationRun is "1997 - Present" for a running series and the start
+// year alone once it has ended, as the end year is not known.
+func publicationRun(s Series) string {
+	switch {
+	case s.Year == 0:
+		return ""
+	case s.Status == StatusCompleted || s.Status == StatusCancelled:
+		return strconv.Itoa(s.Year)
+	}
+	return strconv.Itoa(s.Year) + " - Present"
+}
+
+// writeSeriesJSON creates or updates dir/series.json. A field is refreshed
+// only while it still holds what was generated last time (kept in a hidden
+// snapshot next to it); a value edited by hand stays as is.
+func writeSeriesJSON(dir string, s Series) error {
+	generated, err := seriesJSONFields(s)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, seriesJSONName)
+	doc := map[string]any{}
+	if err := readJSONFile(path, &doc); err != nil {
+		return err
+	}
+	current, _ := doc["metadata"].(map[string]any)
+	if current == nil {
+		current = map[string]any{}
+	}
+	previous := map[string]any{}
+	if err := readJSONFile(filepath.Join(dir, seriesJSONSnapshot), &previous); err != nil {
+		return err
+	}
+
+	for key, value := range generated {
+		old, exists := current[key]
+		last, generatedBefore := previous[key]
+		if exists && old != nil && (!generatedBefore || !reflect.DeepEqual(old, last)) {
+			continue
+		}
+		current[key] = value
+	}
+	doc["metadata"] = current
+	if _, ok := doc["version"]; !ok {
+		doc["version"] = seriesJSONVersion
+	}
+
+	if err := writeJSONFile(path, doc); err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(dir, seriesJSONSnapshot), generated)
+}
+
+// readJSONFile decodes path into out; a missing file leaves out untouched.
+func readJSONFile(path string, out any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("разбор %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
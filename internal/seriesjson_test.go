@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readSeriesJSON(t *testing.T, dir string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "series.json"))
+	if err != nil {
+		t.Fatalf("read series.json: %v", err)
+	}
+	var doc struct {
+		Version  string         `json:"version"`
+		Metadata map[string]any `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("decode series.json: %v", err)
+	}
+	if doc.Version != "1.0.2" {
+		t.Fatalf("version = %q", doc.Version)
+	}
+	return doc.Metadata
+}
+
+func TestWriteSeriesJSON(t *testing.T) {
+	dir := t.TempDir()
+	series := Series{
+		Title:       "Ван-Пис",
+		Publishers:  []string{"Shueisha"},
+		Description: "Пираты.",
+		Year:        1997,
+		Status:      StatusOngoing,
+		AgeRating:   AgeRatingEveryone,
+	}
+	if err := writeSeriesJSON(dir, series); err != nil {
+		t.Fatalf("writeSeriesJSON error: %v", err)
+	}
+	got := readSeriesJSON(t, dir)
+	want := map[string]any{
+		"type": "comicSeries", "name": "Ван-Пис", "booktype": "GN", "publisher": "Shueisha",
+		"year": 1997.0, "description_text": "Пираты.", "status": "Continuing", "age_rating": "All",
+		"total_issues": 0.0, "publication_run": "1997 - Present",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Fatalf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+
+	// A hand-edited description and an extra field survive the next volume,
+	// while untouched fields follow the provider.
+	got["description_text"] = "Своё описание."
+	got["imprint"] = "Jump Comics"
+	data, _ := json.Marshal(map[string]any{"version": "1.0.2", "metadata": got})
+	if err := os.WriteFile(filepath.Join(dir, "series.json"), data, 0o644); err != nil {
+		t.Fatalf("write series.json: %v", err)
+	}
+
+	series.Status = StatusCompleted
+	series.VolumeCount = 107
+	series.Description = "Новое описание."
+	if err := writeSeriesJSON(dir, series); err != nil {
+		t.Fatalf("writeSeriesJSON error: %v", err)
+	}
+	got = readSeriesJSON(t, dir)
+	if got["description_text"] != "Своё описание." || got["imprint"] != "Jump Comics" {
+		t.Fatalf("manual edits lost: %v", got)
+	}
+	if got["status"] != "Ended" || got["total_issues"] != 107.0 || got["publication_run"] != "1997" {
+		t.Fatalf("generated fields not refreshed: %v", got)
+	}
+}
@@ -6,72 +6,249 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-type Metadata struct {
-	Title       string
-	Author      string
-	Description string
-	Genres      string
-	URL         string
-	CoverURL    string
+type shikimoriResponse struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Russian string `json:"russian"`
+	// English and Japanese are only filled by the detail endpoint.
+	English     []string       `json:"english"`
+	Japanese    []string       `json:"japanese"`
+	URL         string         `json:"url"`
+	Image       shikimoriImage `json:"image"`
+	AiredOn     string         `json:"aired_on"`
+	Status      string         `json:"status"`
+	Volumes     int            `json:"volumes"`
+	Chapters    int            `json:"chapters"`
+	Description string         `json:"description"`
+	Genres      []string       `json:"genres"`
 }
 
-type shikimoriResponse struct {
+type shikimoriImage struct {
+	Original string `json:"original"`
+	Preview  string `json:"preview"`
+}
+
+type shikimoriGenre struct {
 	Name    string `json:"name"`
 	Russian string `json:"russian"`
-	URL     string `json:"url"`
-	Image   struct {
-		Original string `json:"original"`
-	} `json:"image"`
-	Description string   `json:"description"`
-	Genres      []string `json:"genres"`
+	// Kind is "genre", "theme" or "demographic".
+	Kind string `json:"kind"`
+}
+
+// shikimoriDetail is the /api/mangas/{id} response; unlike the search one its
+// genres are objects.
+type shikimoriDetail struct {
+	shikimoriResponse
+	MyAnimeListID int              `json:"myanimelist_id"`
+	Genres        []shikimoriGenre `json:"genres"`
+	Publishers    []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+}
+
+// shikimoriRole is an entry of /api/mangas/{id}/roles: either a character or
+// a person with roles such as "Story & Art".
+type shikimoriRole struct {
+	Roles  []string `json:"roles"`
+	Person *struct {
+		Name string `json:"name"`
+	} `json:"person"`
 }
 
 func FetchMetadata(name string) (*Metadata, error) {
 	query := strings.ReplaceAll(name, "_", " ")
 	log.Printf("🔎 Запрос Shikimori по имени: %s", query)
 
-	req, err := http.NewRequest("GET", "https://shikimori.one/api/mangas", nil)
+	var results []shikimoriResponse
+	if err := shikimoriGet("/api/mangas", url.Values{"search": {query}}, &results); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, errors.New("манга не найдена")
+	}
+	if results[0].ID == 0 {
+		return newMetadata(results[0].series(), query), nil
+	}
+	meta, err := fetchShikimoriDetail(results[0].ID, query)
 	if err != nil {
+		log.Printf("⚠️ Подробности Shikimori #%d не загружены, берём данные поиска: %v", results[0].ID, err)
+		return newMetadata(results[0].series(), query), nil
+	}
+	return meta, nil
+}
+
+// FetchShikimoriByID fetches a series by its Shikimori ID, skipping the
+// search; name is the folder name used as the fallback title.
+func FetchShikimoriByID(id int, name string) (*Metadata, error) {
+	log.Printf("🔎 Запрос Shikimori по ID: %d", id)
+	return fetchShikimoriDetail(id, strings.ReplaceAll(name, "_", " "))
+}
+
+// fetchShikimoriDetail reads the detail endpoint and the staff roles, which
+// the search response lacks. Missing roles only cost the authors.
+func fetchShikimoriDetail(id int, folder string) (*Metadata, error) {
+	path := "/api/mangas/" + strconv.Itoa(id)
+	var detail shikimoriDetail
+	if err := shikimoriGet(path, nil, &detail); err != nil {
 		return nil, err
 	}
+	manga := detail.shikimoriResponse
+	var themes []string
+	for _, g := range detail.Genres {
+		if g.Kind == "" || g.Kind == "genre" {
+			manga.Genres = append(manga.Genres, g.Name)
+		} else {
+			themes = append(themes, g.Name)
+		}
+	}
+	series := manga.series()
+	series.Tags = themes
+	series.IDs.MyAnimeList = detail.MyAnimeListID
+	for _, p := range detail.Publishers {
+		series.Publishers = append(series.Publishers, p.Name)
+	}
 
-	q := req.URL.Query()
-	q.Add("search", query)
-	req.URL.RawQuery = q.Encode()
-	req.Header.Set("User-Agent", "manga-converter")
+	var roles []shikimoriRole
+	if err := shikimoriGet(path+"/roles", nil, &roles); err != nil {
+		log.Printf("⚠️ Авторы Shikimori #%d не загружены: %v", id, err)
+	}
+	for _, r := range roles {
+		if r.Person == nil {
+			continue
+		}
+		for _, role := range r.Roles {
+			series.addCreator(r.Person.Name, role)
+		}
+	}
+	return newMetadata(series, folder), nil
+}
+
+// shikimoriInterval spaces API requests to stay under the Shikimori limits
+// of 5 requests per second and 90 per minute; a retag of a whole library
+// would hit them otherwise.
+var shikimoriInterval = 700 * time.Millisecond
+
+var shikimoriThrottle struct {
+	sync.Mutex
+	next time.Time
+}
 
-	resp, err := http.DefaultClient.Do(req)
+func waitShikimori() {
+	shikimoriThrottle.Lock()
+	defer shikimoriThrottle.Unlock()
+	time.Sleep(time.Until(shikimoriThrottle.next))
+	shikimoriThrottle.next = time.Now().Add(shikimoriInterval)
+}
+
+func shikimoriGet(path string, query url.Values, out any) error {
+	req, err := http.NewRequest("GET", "https://shikimori.one"+path, nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("User-Agent", "manga-converter")
+
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		waitShikimori()
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == 3 {
+			break
+		}
+		resp.Body.Close()
+		wait := time.Duration(attempt) * time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+		log.Printf("⏳ Shikimori ограничивает частоту запросов, повтор через %s", wait)
+		time.Sleep(wait)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("статус ответа %d", resp.StatusCode)
+		return fmt.Errorf("статус ответа %d", resp.StatusCode)
 	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
 
-	var results []shikimoriResponse
-	err = json.NewDecoder(resp.Body).Decode(&results)
-	if err != nil {
-		return nil, err
+func (manga shikimoriResponse) series() Series {
+	series := Series{
+		Titles: Titles{
+			Russian: manga.Russian,
+			English: firstOf(manga.English),
+			Romaji:  manga.Name,
+			Native:  firstOf(manga.Japanese),
+		},
+		Description:  plainDescription(manga.Description),
+		Genres:       manga.Genres,
+		Year:         yearOf(manga.AiredOn),
+		Status:       shikimoriStatuses[manga.Status],
+		AgeRating:    ratingByGenres(manga.Genres),
+		VolumeCount:  manga.Volumes,
+		ChapterCount: manga.Chapters,
+		IDs:          ProviderIDs{Shikimori: manga.ID},
 	}
-
-	if len(results) == 0 {
-		return nil, errors.New("манга не найдена")
+	if manga.URL != "" {
+		series.URL = "https://shikimori.one" + manga.URL
+	}
+	for _, image := range []string{manga.Image.Original, manga.Image.Preview} {
+		if image != "" {
+			series.Covers = append(series.Covers, Cover{URL: "https://shikimori.one" + image})
+		}
 	}
+	return series
+}
+
+var shikimoriStatuses = map[string]string{
+	"anons":        StatusAnnounced,
+	"ongoing":      StatusOngoing,
+	"released":     StatusCompleted,
+	"paused":       StatusHiatus,
+	"discontinued": StatusCancelled,
+}
 
-	manga := results[0]
-	genres := strings.Join(manga.Genres, ", ")
+var (
+	bbcodeBreak  = regexp.MustCompile(`(?i)\[br\]`)
+	bbcodeSource = regexp.MustCompile(`(?is)\[source\].*?\[/source\]`)
+	bbcodeTag    = regexp.MustCompile(`\[/?[a-z_]+(?:=[^\]]*)?\]`)
+	wikiLink     = regexp.MustCompile(`\[\[([^\]]*)\]\]`)
+	blankLines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// plainDescription strips Shikimori markup such as "[character=1]Луффи
+// [/character]" or "[[Ван-Пис]]", keeping the text; source credits are
+// dropped.
+func plainDescription(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = bbcodeBreak.ReplaceAllString(s, "\n")
+	s = bbcodeSource.ReplaceAllString(s, "")
+	s = wikiLink.ReplaceAllString(s, "$1")
+	s = bbcodeTag.ReplaceAllString(s, "")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+func firstOf(list []string) string {
+	if len(list) > 0 {
+		return list[0]
+	}
+	return ""
+}
 
-	return &Metadata{
-		Title:       manga.Russian,
-		Author:      "", // Shikimori не всегда указывает
-		Description: manga.Description,
-		Genres:      genres,
-		URL:         "https://shikimori.one" + manga.URL,
-		CoverURL:    "https://shikimori.one" + manga.Image.Original,
-	}, nil
+// yearOf takes the year from a "2006-01-02" date, 0 if there is none.
+func yearOf(date string) int {
+	year, _ := strconv.Atoi(date[:min(4, len(date))])
+	return year
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -31,14 +33,14 @@ func TestFetchMetadata(t *testing.T) {
 	if meta.Title != "Боевая классика" {
 		t.Fatalf("unexpected title: %s", meta.Title)
 	}
-	if meta.URL != "https://shikimori.one/mangas/42" {
-		t.Fatalf("unexpected url: %s", meta.URL)
+	if meta.Series.URL != "https://shikimori.one/mangas/42" {
+		t.Fatalf("unexpected url: %s", meta.Series.URL)
 	}
-	if meta.Genres != "Action, Adventure" {
-		t.Fatalf("unexpected genres: %s", meta.Genres)
+	if want := []string{"Action", "Adventure"}; !reflect.DeepEqual(meta.Series.Genres, want) {
+		t.Fatalf("unexpected genres: %v", meta.Series.Genres)
 	}
-	if meta.CoverURL != "https://shikimori.one/covers/42.jpg" {
-		t.Fatalf("unexpected cover url: %s", meta.CoverURL)
+	if meta.Series.Cover() != "https://shikimori.one/covers/42.jpg" {
+		t.Fatalf("unexpected cover url: %s", meta.Series.Cover())
 	}
 }
 
@@ -69,3 +71,122 @@ func TestFetchMetadataHTTPError(t *testing.T) {
 		t.Fatal("expected error on non-200 status")
 	}
 }
+
+func TestShikimoriRetriesTooManyRequests(t *testing.T) {
+	calls := 0
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`[{"id": 1, "name": "Test", "russian": "Тест"}]`)),
+			Header:     make(http.Header),
+		}
+		if calls == 1 {
+			resp.StatusCode = http.StatusTooManyRequests
+			resp.Header.Set("Retry-After", "0")
+		}
+		return resp, nil
+	}))
+
+	var found []shikimoriResponse
+	if err := shikimoriGet("/api/mangas", nil, &found); err != nil {
+		t.Fatalf("shikimoriGet error: %v", err)
+	}
+	if calls != 2 || len(found) != 1 {
+		t.Fatalf("calls = %d, found = %+v", calls, found)
+	}
+}
+
+func TestFetchMetadataDetail(t *testing.T) {
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body string
+		switch req.URL.Path {
+		case "/api/mangas":
+			body = `[{"id": 13, "russian": "Ван-Пис", "genres": ["Action"]}]`
+		case "/api/mangas/13":
+			body = `{"id": 13, "name": "One Piece", "russian": "Ван-Пис", "english": ["One Piece"], "japanese": ["ワンピース"],
+				"status": "released", "volumes": 100, "chapters": 1100, "aired_on": "1997-07-22",
+				"description": "Пират [character=40]Луффи[/character] ищет [[Ван-Пис]].[br]Конец.[source]wiki[/source]",
+				"myanimelist_id": 13, "genres": [{"name": "Action", "russian": "Экшен", "kind": "genre"}, {"name": "Shounen", "kind": "demographic"}], "publishers": [{"name": "Shonen Jump"}]}`
+		case "/api/mangas/13/roles":
+			body = `[{"roles": ["Story & Art"], "person": {"name": "Eiichiro Oda"}},
+				{"roles": ["Main"], "character": {"name": "Luffy"}, "person": null},
+				{"roles": ["Art"], "person": {"name": "Assistant"}}]`
+		default:
+			t.Fatalf("unexpected path: %s", req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(body)),
+			Header:     make(http.Header),
+		}, nil
+	}))
+
+	meta, err := FetchMetadata("One Piece")
+	if err != nil {
+		t.Fatalf("FetchMetadata error: %v", err)
+	}
+	series := meta.Series
+	if w, a := series.People(RoleWriter), series.People(RoleArtist); !reflect.DeepEqual(w, []string{"Eiichiro Oda"}) || !reflect.DeepEqual(a, []string{"Eiichiro Oda", "Assistant"}) {
+		t.Fatalf("unexpected staff: %v / %v", w, a)
+	}
+	if !reflect.DeepEqual(series.Publishers, []string{"Shonen Jump"}) || series.Status != StatusCompleted || series.Year != 1997 {
+		t.Fatalf("unexpected publisher/status/year: %v %q %d", series.Publishers, series.Status, series.Year)
+	}
+	if series.VolumeCount != 100 || series.ChapterCount != 1100 {
+		t.Fatalf("unexpected counts: %d %d", series.VolumeCount, series.ChapterCount)
+	}
+	if !reflect.DeepEqual(series.Genres, []string{"Action"}) || !reflect.DeepEqual(series.Tags, []string{"Shounen"}) {
+		t.Fatalf("unexpected genres/tags: %v / %v", series.Genres, series.Tags)
+	}
+	if want := (ProviderIDs{Shikimori: 13, MyAnimeList: 13}); series.IDs != want {
+		t.Fatalf("IDs = %+v, want %+v", series.IDs, want)
+	}
+	if want := "Пират Луффи ищет Ван-Пис.\nКонец."; series.Description != want {
+		t.Fatalf("Description = %q, want %q", series.Description, want)
+	}
+}
+
+func TestFetchMetadataDetailFailure(t *testing.T) {
+	stubHTTPClient(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/api/mangas" {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(bytes.NewBuffer(nil)), Header: make(http.Header)}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`[{"id": 13, "russian": "Ван-Пис"}]`)),
+			Header:     make(http.Header),
+		}, nil
+	}))
+
+	meta, err := FetchMetadata("One Piece")
+	if err != nil || meta.Title != "Ван-Пис" {
+		t.Fatalf("search hit should be used when the detail fails, got %+v, %v", meta, err)
+	}
+}
+
+func TestComicInfoStaff(t *testing.T) {
+	meta := &Metadata{Title: "T — Том 1", Language: "ru", Series: Series{
+		Title:       "T",
+		Creators:    []Creator{{"Writer", RoleWriter}, {"Painter", RoleArtist}},
+		Publishers:  []string{"Shueisha"},
+		Genres:      []string{"Action", "Drama"},
+		Tags:        []string{"School"},
+		Year:        2001,
+		AgeRating:   AgeRatingEveryone,
+		VolumeCount: 12,
+	}}
+	data, err := buildComicInfo(nil, meta)
+	if err != nil {
+		t.Fatalf("buildComicInfo error: %v", err)
+	}
+	for _, want := range []string{
+		"<Series>T</Series>", "<Count>12</Count>", "<Year>2001</Year>",
+		"<Writer>Writer</Writer>", "<Penciller>Painter</Penciller>", "<Publisher>Shueisha</Publisher>",
+		"<Genre>Action, Drama</Genre>", "<Tags>School</Tags>", "<LanguageISO>ru</LanguageISO>", "<AgeRating>Everyone</AgeRating>",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("ComicInfo.xml missing %s, got %s", want, data)
+		}
+	}
+}
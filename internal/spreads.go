@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"image"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IsSpread reports whether the page is a landscape two-page spread.
+func (p *Page) IsSpread() bool {
+	return p.Width > p.Height
+}
+
+// applySpreads handles landscape pages according to profile.Spreads. Halves
+// are ordered right-to-left or left-to-right following the reading direction.
+// Broken pages cannot be cut and are passed through unchanged.
+func applySpreads(pages []*Page, profile OutputProfile, dir string) ([]*Page, error) {
+	halves := make([][]*Page, len(pages))
+	if profile.Spreads != SpreadKeep {
+		err := forEachPage(pages, func(i int, p *Page) error {
+			if !p.IsSpread() || p.Problem != "" {
+				return nil
+			}
+			var err error
+			halves[i], err = splitSpread(p, profile.ReadingDirection, dir)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]*Page, 0, len(pages))
+	for i, p := range pages {
+		page := *p
+		if !page.IsSpread() || page.Problem != "" {
+			out = append(out, &page)
+			continue
+		}
+
+		if profile.Spreads == SpreadKeep || profile.Spreads == SpreadBoth {
+			page.DoublePage = true
+			out = append(out, &page)
+		}
+		if profile.Spreads == SpreadKeep {
+			continue
+		}
+		if profile.Spreads == SpreadBoth {
+			// The kept spread comes first and holds the bookmark.
+			first := *halves[i][0]
+			first.Bookmark = ""
+			halves[i][0] = &first
+		}
+
+		log.Printf("✂️ Разворот %s разделён на две страницы", p.Name)
+		out = append(out, halves[i]...)
+	}
+	return out, nil
+}
+
+func splitSpread(p *Page, direction string, dir string) ([]*Page, error) {
+	img, _, err := decodeImage(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	sub, ok := img.(subImager)
+	if !ok {
+		return []*Page{p}, nil
+	}
+
+	b := img.Bounds()
+	mid := b.Min.X + b.Dx()/2
+	left := image.Rect(b.Min.X, b.Min.Y, mid, b.Max.Y)
+	right := image.Rect(mid, b.Min.Y, b.Max.X, b.Max.Y)
+	order := []image.Rectangle{right, left}
+	if direction == DirectionLTR {
+		order = []image.Rectangle{left, right}
+	}
+
+	ext := path.Ext(p.Name)
+	base := strings.TrimSuffix(p.Name, ext)
+	halves := make([]*Page, 0, 2)
+	for i, rect := range order {
+		// "010.jpg" becomes "010_1.jpg" and "010_2.jpg", which sort right
+		// after the original and before "011.jpg".
+		name := base + "_" + string(rune('1'+i)) + ext
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := encodeImage(target, sub.SubImage(rect)); err != nil {
+			return nil, err
+		}
+		halves = append(halves, &Page{
+			Path:           target,
+			Name:           name,
+			Width:          rect.Dx(),
+			Height:         rect.Dy(),
+			OriginalWidth:  p.OriginalWidth,
+			OriginalHeight: p.OriginalHeight,
+		})
+	}
+	halves[0].Bookmark = p.Bookmark
+	return halves, nil
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// spreadPage returns a landscape page whose left half is black and right half
+// is white.
+func spreadPage(t *testing.T, dir string) *Page {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			if x >= 20 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	path := filepath.Join(dir, "010.png")
+	writeImageFile(t, path, img)
+	return &Page{Path: path, Name: "010.png", Width: 40, Height: 20, OriginalWidth: 40, OriginalHeight: 20}
+}
+
+func pageLuma(t *testing.T, p *Page) uint8 {
+	t.Helper()
+	img, _, err := decodeImage(p.Path)
+	if err != nil {
+		t.Fatalf("decode %s: %v", p.Path, err)
+	}
+	b := img.Bounds()
+	return luma(img, b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2)
+}
+
+func TestApplySpreadsKeep(t *testing.T) {
+	dir := t.TempDir()
+	portrait := &Page{Name: "009.png", Width: 20, Height: 40}
+	pages := []*Page{portrait, spreadPage(t, dir)}
+
+	got, err := applySpreads(pages, OutputProfile{Spreads: SpreadKeep, ReadingDirection: DirectionRTL}, t.TempDir())
+	if err != nil {
+		t.Fatalf("applySpreads error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d pages, want 2", len(got))
+	}
+	if got[0].DoublePage || !got[1].DoublePage {
+		t.Fatalf("DoublePage flags = %v, %v; want false, true", got[0].DoublePage, got[1].DoublePage)
+	}
+	if pages[1].DoublePage {
+		t.Fatal("source page must not be modified")
+	}
+}
+
+func TestApplySpreadsSplitRTL(t *testing.T) {
+	pages := []*Page{spreadPage(t, t.TempDir())}
+
+	got, err := applySpreads(pages, OutputProfile{Spreads: SpreadSplit, ReadingDirection: DirectionRTL}, t.TempDir())
+	if err != nil {
+		t.Fatalf("applySpreads error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d pages, want 2", len(got))
+	}
+	if got[0].Name != "010_1.png" || got[1].Name != "010_2.png" {
+		t.Fatalf("names = %s, %s", got[0].Name, got[1].Name)
+	}
+	if got[0].Width != 20 || got[0].Height != 20 {
+		t.Fatalf("half size = %dx%d, want 20x20", got[0].Width, got[0].Height)
+	}
+	// Right-to-left: the white right half comes first.
+	if pageLuma(t, got[0]) != 255 || pageLuma(t, got[1]) != 0 {
+		t.Fatal("halves are not ordered right-to-left")
+	}
+}
+
+func TestApplySpreadsBothLTR(t *testing.T) {
+	pages := []*Page{spreadPage(t, t.TempDir())}
+	pages[0].Bookmark = "Глава 1"
+
+	got, err := applySpreads(pages, OutputProfile{Spreads: SpreadBoth, ReadingDirection: DirectionLTR}, t.TempDir())
+	if err != nil {
+		t.Fatalf("applySpreads error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d pages, want 3", len(got))
+	}
+	if !got[0].DoublePage || got[0].Name != "010.png" {
+		t.Fatalf("first page should be the kept spread, got %+v", got[0])
+	}
+	if pageLuma(t, got[1]) != 0 || pageLuma(t, got[2]) != 255 {
+		t.Fatal("halves are not ordered left-to-right")
+	}
+	if got[0].Bookmark != "Глава 1" || got[1].Bookmark != "" {
+		t.Fatalf("bookmark should stay on the spread only, got %q and %q", got[0].Bookmark, got[1].Bookmark)
+	}
+}
+
+func TestApplySpreadsSkipsBrokenPages(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "010.jpg")
+	writeJPEG(t, path, 600, 300)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read jpeg: %v", err)
+	}
+	if err := os.WriteFile(path, data[:len(data)*9/10], 0o644); err != nil {
+		t.Fatalf("write truncated: %v", err)
+	}
+
+	pages, err := ValidatePages([]*Page{{Path: path, Name: "010.jpg", Width: 600, Height: 300}}, ValidationWarn, &VolumeReport{})
+	if err != nil {
+		t.Fatalf("ValidatePages error: %v", err)
+	}
+	got, err := profilePages(pages, OutputProfile{Spreads: SpreadSplit, ReadingDirection: DirectionRTL}, t.TempDir())
+	if err != nil {
+		t.Fatalf("profilePages error: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "010.jpg" || got[0].DoublePage {
+		t.Fatalf("broken spread should be packed as is, got %+v", got)
+	}
+}
@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	ProviderMangaDex = "mangadex"
+	ProviderNone     = "none"
+)
+
+// AccumulateSettings hold chapter archives in a staging area until every
+// chapter of a volume has arrived.
+type AccumulateSettings struct {
+	Enabled bool `json:"enabled"`
+	// Staging keeps one folder per series with one subfolder per chapter.
+	Staging string `json:"staging"`
+	// Mapping is a JSON file {"series": {"volume": ["1-8", "8.5"]}} that
+	// takes precedence over the provider.
+	Mapping string `json:"mapping"`
+	// Provider supplies the volume/chapter mapping: "mangadex" or "none".
+	Provider string `json:"provider"`
+}
+
+// VolumeStatus lists the staged and still missing chapters of a volume.
+type VolumeStatus struct {
+	Volume  string
+	Staged  []string
+	Missing []string
+}
+
+// SeriesStatus describes the staged chapters of one series.
+type SeriesStatus struct {
+	Series  string
+	Volumes []VolumeStatus
+	// Unassigned chapters belong to no known volume yet.
+	Unassigned []string
+	Error      string
+}
+
+// stagingLocks holds a *sync.Mutex per series staging folder: the watcher
+// processes archives concurrently, and two chapters of one series must not
+// build the same volume twice.
+var stagingLocks sync.Map
+
+func lockSeries(seriesDir string) func() {
+	m, _ := stagingLocks.LoadOrStore(seriesDir, &sync.Mutex{})
+	mu := m.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// accumulateChapters moves the chapter folders of an archive to the staging
+// area and builds every volume whose chapters are now all present.
+func accumulateChapters(mangaRoot string, chapters []string, meta *Metadata, report *JobReport) error {
+	series := sanitizeName(seriesName(filepath.Base(mangaRoot)), CharsetUnicode)
+	seriesDir := filepath.Join(Config.Accumulate.Staging, series)
+	defer lockSeries(seriesDir)()
+	for _, name := range chapters {
+		number := chapterNumber(name, false)
+		if number == "" {
+			return fmt.Errorf("не удалось определить номер главы %q", name)
+		}
+		if err := stageChapter(filepath.Join(mangaRoot, name), seriesDir, number); err != nil {
+			return fmt.Errorf("глава %s: %w", number, err)
+		}
+		log.Printf("📥 Глава %s серии %s добавлена в накопитель", number, series)
+	}
+
+	status := seriesStatus(series)
+	if status.Error != "" {
+		log.Printf("⚠️ Не удалось получить состав томов %s, главы ждут в накопителе: %s", series, status.Error)
+		return nil
+	}
+	for _, vs := range status.Volumes {
+		if len(vs.Missing) > 0 {
+			log.Printf("⏳ Том %s: не хватает глав %s", vs.Volume, strings.Join(vs.Missing, ", "))
+			continue
+		}
+		volReport := report.Volume(vs.Volume)
+		if err := buildStagedVolume(seriesDir, vs, meta, volReport); err != nil {
+			volReport.Error = err.Error()
+			log.Printf("❌ Ошибка тома %s: %v", vs.Volume, err)
+		} else {
+			log.Printf("✅ Том %s собран из накопленных глав", vs.Volume)
+		}
+	}
+	return nil
+}
+
+// stageChapter moves a chapter folder into the series staging folder. The
+// chapter arrives under a temporary name first, so a failed move never costs
+// a copy of it that was staged before.
+func stageChapter(src, seriesDir, number string) error {
+	if err := os.MkdirAll(seriesDir, os.ModePerm); err != nil {
+		return err
+	}
+	target := filepath.Join(seriesDir, chapterDirName(number))
+	incoming := filepath.Join(seriesDir, ".incoming-"+chapterDirName(number))
+	replaced := filepath.Join(seriesDir, ".replaced-"+chapterDirName(number))
+	// Leftovers of an interrupted run.
+	os.RemoveAll(incoming)
+	os.RemoveAll(replaced)
+
+	if err := moveDir(src, incoming); err != nil {
+		return err
+	}
+	if _, err := os.Stat(target); err == nil {
+		log.Printf("♻️ Глава %s уже в накопителе, заменяем", number)
+		if err := os.Rename(target, replaced); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(incoming, target); err != nil {
+		return err
+	}
+	return os.RemoveAll(replaced)
+}
+
+// moveDir renames a folder, copying it when the target is on another volume.
+func moveDir(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyDir(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// buildStagedVolume converts the staged chapters of a complete volume and
+// removes them from the staging area.
+func buildStagedVolume(seriesDir string, vs VolumeStatus, meta *Metadata, report *VolumeReport) error {
+	var pages []*Page
+	for _, number := range vs.Staged {
+		dir := chapterDirName(number)
+		chapterPages, err := LoadPages(filepath.Join(seriesDir, dir))
+		if err != nil {
+			return fmt.Errorf("чтение главы %s: %w", number, err)
+		}
+		for _, p := range chapterPages {
+			p.Name = dir + "/" + p.Name
+		}
+		if len(chapterPages) > 0 {
+			chapterPages[0].Bookmark = "Глава " + number
+		}
+		pages = append(pages, chapterPages...)
+	}
+
+	if err := convertPages(pages, vs.Volume, filepath.Base(seriesDir), meta, report); err != nil {
+		return err
+	}
+	for _, number := range vs.Staged {
+		os.RemoveAll(filepath.Join(seriesDir, chapterDirName(number)))
+	}
+	return nil
+}
+
+// StagingStatus lists what every staged series is still waiting for.
+func StagingStatus() ([]SeriesStatus, error) {
+	entries, err := os.ReadDir(Config.Accumulate.Staging)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result []SeriesStatus
+	for _, entry := range entries {
+		if entry.IsDir() {
+			result = append(result, seriesStatus(entry.Name()))
+		}
+	}
+	return result, nil
+}
+
+func seriesStatus(series string) SeriesStatus {
+	status := SeriesStatus{Series: series}
+	staged, err := stagedChapters(filepath.Join(Config.Accumulate.Staging, series))
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	mapping, err := volumeMapping(series)
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	assigned := map[string]bool{}
+	for volume, chapters := range mapping {
+		vs := VolumeStatus{Volume: volume}
+		for _, c := range chapters {
+			assigned[c] = true
+			if staged[c] {
+				vs.Staged = append(vs.Staged, c)
+			} else {
+				vs.Missing = append(vs.Missing, c)
+			}
+		}
+		// Volumes without staged chapters are either built or not started.
+		if len(vs.Staged) > 0 {
+			slices.SortFunc(vs.Staged, compareNumbers)
+			slices.SortFunc(vs.Missing, compareNumbers)
+			status.Volumes = append(status.Volumes, vs)
+		}
+	}
+	for c := range staged {
+		if !assigned[c] {
+			status.Unassigned = append(status.Unassigned, c)
+		}
+	}
+	slices.SortFunc(status.Volumes, func(a, b VolumeStatus) int { return compareNumbers(a.Volume, b.Volume) })
+	slices.SortFunc(status.Unassigned, compareNumbers)
+	return status
+}
+
+// stagedChapters returns the chapter numbers present in a series folder.
+func stagedChapters(seriesDir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(seriesDir)
+	if err != nil {
+		return nil, err
+	}
+	staged := map[string]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() && firstNumber.FindString(entry.Name()) == entry.Name() {
+			staged[trimNumber(entry.Name())] = true
+		}
+	}
+	return staged, nil
+}
+
+// volumeMapping returns the chapters of every volume of the series, taken
+// from the mapping file or, failing that, from the provider.
+func volumeMapping(series string) (map[string][]string, error) {
+	mapping, err := loadChapterMapping(Config.Accumulate.Mapping, series)
+	if err != nil || mapping != nil {
+		return mapping, err
+	}
+	if Config.Accumulate.Provider == ProviderMangaDex {
+		return FetchMangaDexVolumes(series)
+	}
+	return nil, nil
+}
+
+func loadChapterMapping(path, series string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]map[string][]string
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("разбор %s: %w", path, err)
+	}
+	volumes, ok := all[series]
+	if !ok {
+		return nil, nil
+	}
+
+	mapping := map[string][]string{}
+	for volume, specs := range volumes {
+		for _, spec := range specs {
+			chapters, err := expandChapters(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s, том %s: %w", path, volume, err)
+			}
+			mapping[trimNumber(volume)] = append(mapping[trimNumber(volume)], chapters...)
+		}
+	}
+	return mapping, nil
+}
+
+// expandChapters turns "3" into [3] and "1-4" into [1 2 3 4].
+func expandChapters(spec string) ([]string, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+	if !isRange {
+		if firstNumber.FindString(from) != from || from == "" {
+			return nil, fmt.Errorf("неверный номер главы %q", spec)
+		}
+		return []string{trimNumber(from)}, nil
+	}
+	first, err1 := strconv.Atoi(strings.TrimSpace(from))
+	last, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || first > last {
+		return nil, fmt.Errorf("неверный диапазон глав %q", spec)
+	}
+	var chapters []string
+	for n := first; n <= last; n++ {
+		chapters = append(chapters, strconv.Itoa(n))
+	}
+	return chapters, nil
+}
+
+// chapterDirName pads the integer part so staged chapters sort numerically:
+// "5.5" becomes "0005.5".
+func chapterDirName(number string) string {
+	return padNumber(number, 4)
+}
+
+// compareNumbers orders numeric strings by value, other strings after them.
+func compareNumbers(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandChapters(t *testing.T) {
+	got, err := expandChapters("3-5")
+	if err != nil || !reflect.DeepEqual(got, []string{"3", "4", "5"}) {
+		t.Fatalf("expandChapters(3-5) = %v, %v", got, err)
+	}
+	got, err = expandChapters("08.5")
+	if err != nil || !reflect.DeepEqual(got, []string{"8.5"}) {
+		t.Fatalf("expandChapters(08.5) = %v, %v", got, err)
+	}
+	for _, bad := range []string{"5-3", "x", "1-b", ""} {
+		if _, err := expandChapters(bad); err == nil {
+			t.Fatalf("expandChapters(%q) should fail", bad)
+		}
+	}
+	if got := chapterDirName("5.5"); got != "0005.5" {
+		t.Fatalf("chapterDirName(5.5) = %q", got)
+	}
+}
+
+func TestStageChapterReplace(t *testing.T) {
+	seriesDir := filepath.Join(t.TempDir(), "Test")
+	first := filepath.Join(t.TempDir(), "Глава 5")
+	writePNG(t, filepath.Join(first, "001.png"), 4, 4)
+	if err := stageChapter(first, seriesDir, "5"); err != nil {
+		t.Fatalf("stageChapter error: %v", err)
+	}
+
+	// A failed move keeps the chapter staged before.
+	if err := stageChapter(filepath.Join(t.TempDir(), "missing"), seriesDir, "5"); err == nil {
+		t.Fatal("moving a missing folder should fail")
+	}
+	if _, err := os.Stat(filepath.Join(seriesDir, "0005", "001.png")); err != nil {
+		t.Fatalf("staged chapter lost: %v", err)
+	}
+
+	second := filepath.Join(t.TempDir(), "Глава 5")
+	writePNG(t, filepath.Join(second, "002.png"), 4, 4)
+	if err := stageChapter(second, seriesDir, "5"); err != nil {
+		t.Fatalf("stageChapter error: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(seriesDir, "0005"))
+	if err != nil || len(entries) != 1 || entries[0].Name() != "002.png" {
+		t.Fatalf("chapter not replaced: %v %v", entries, err)
+	}
+	if entries, _ := os.ReadDir(seriesDir); len(entries) != 1 {
+		t.Fatalf("temporary folders left behind: %v", entries)
+	}
+}
+
+func TestAccumulateChapters(t *testing.T) {
+	tmp := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	original := Config
+	settings := *Config
+	settings.Accumulate = AccumulateSettings{Enabled: true, Staging: "staging", Mapping: "chapters.json", Provider: ProviderNone}
+	Config = &settings
+	t.Cleanup(func() { Config = original })
+
+	mapping := `{"TestManga": {"1": ["1-2"], "2": ["3"]}}`
+	if err := os.WriteFile("chapters.json", []byte(mapping), 0o644); err != nil {
+		t.Fatalf("write mapping: %v", err)
+	}
+	meta := &Metadata{Title: "Test Title", Series: Series{Title: "Test Title"}}
+
+	// The first archive brings chapters 1 and 4: volume 1 still misses
+	// chapter 2 and chapter 4 has no volume.
+	root := filepath.Join(tmp, "work1", "TestManga")
+	writeJPEG(t, filepath.Join(root, "Глава 1", "001.jpg"), 10, 10)
+	writeJPEG(t, filepath.Join(root, "Глава 4", "001.jpg"), 10, 10)
+	report := NewJobReport("first.zip")
+	if err := accumulateChapters(root, []string{"Глава 1", "Глава 4"}, meta, report); err != nil {
+		t.Fatalf("accumulateChapters error: %v", err)
+	}
+	if len(report.Volumes) != 0 {
+		t.Fatalf("no volume should be built yet: %+v", report.Volumes)
+	}
+
+	statuses, err := StagingStatus()
+	if err != nil {
+		t.Fatalf("StagingStatus error: %v", err)
+	}
+	want := []SeriesStatus{{
+		Series:     "TestManga",
+		Volumes:    []VolumeStatus{{Volume: "1", Staged: []string{"1"}, Missing: []string{"2"}}},
+		Unassigned: []string{"4"},
+	}}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("StagingStatus = %+v, want %+v", statuses, want)
+	}
+
+	root = filepath.Join(tmp, "work2", "TestManga")
+	writeJPEG(t, filepath.Join(root, "Chapter 02", "001.jpg"), 10, 10)
+	report = NewJobReport("second.zip")
+	if err := accumulateChapters(root, []string{"Chapter 02"}, meta, report); err != nil {
+		t.Fatalf("accumulateChapters error: %v", err)
+	}
+	if len(report.Volumes) != 1 || report.Volumes[0].Error != "" {
+		t.Fatalf("volume 1 should be built: %+v", report.Volumes)
+	}
+
+	_, contents := readZipEntries(t, filepath.Join("output", "cbz", "Test Title", "TestManga__Vol_01.cbz"))
+	if _, ok := contents["0002/001.jpg"]; !ok {
+		t.Fatalf("chapter 2 missing from the volume: %v", contents)
+	}
+	if info := contents["ComicInfo.xml"]; !strings.Contains(info, `Bookmark="Глава 1"`) || !strings.Contains(info, `Bookmark="Глава 2"`) {
+		t.Fatalf("ComicInfo.xml missing chapter bookmarks, got %s", info)
+	}
+
+	for _, dir := range []string{"0001", "0002"} {
+		if _, err := os.Stat(filepath.Join("staging", "TestManga", dir)); !os.IsNotExist(err) {
+			t.Fatalf("built chapter %s should leave the staging area: %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join("staging", "TestManga", "0004")); err != nil {
+		t.Fatalf("unassigned chapter should stay staged: %v", err)
+	}
+}
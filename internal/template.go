@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// NamingSettings are the output path templates. Dir is relative to output/
+// and may contain "/"; File is the book name without extension.
+//
+// Fields are written as {name} or {name:0N} for zero padding to N digits.
+// A part in angle brackets is dropped when any field inside it is empty:
+// "{series}< v{volume:02}>" gives "Title v03" or just "Title".
+type NamingSettings struct {
+	Dir  string `json:"dir"`
+	File string `json:"file"`
+	// Charset is CharsetUnicode or CharsetASCII.
+	Charset string `json:"charset"`
+}
+
+const (
+	DefaultDirTemplate  = "{profile}/{series}"
+	DefaultFileTemplate = "{folder}__{book}<_Part_{part}>"
+)
+
+// templateFields lists the fields known to templates:
+//
+//	series  - series title from the provider
+//	folder  - manga folder name without release tags
+//	book    - "Vol_01", "Vol_02__Chapter_004", "Omnibus"
+//	volume  - volume number
+//	chapter - chapter number or range
+//	year    - year the series started
+//	group   - scanlation group
+//	format  - cbz or epub
+//	profile - output profile name
+//	part    - part number of a split volume
+var templateFields = []string{"series", "folder", "book", "volume", "chapter", "year", "group", "format", "profile", "part"}
+
+type templateToken struct {
+	text     string // literal text when field is empty
+	field    string
+	width    int // zero padding
+	optional int // index of the optional part, -1 outside
+}
+
+func parseTemplate(tmpl string) ([]templateToken, error) {
+	var tokens []templateToken
+	optional, groups := -1, 0
+	for rest := tmpl; rest != ""; {
+		switch rest[0] {
+		case '<':
+			if optional >= 0 {
+				return nil, errors.New("вложенные необязательные части не поддерживаются")
+			}
+			optional, groups = groups, groups+1
+			rest = rest[1:]
+		case '>':
+			if optional < 0 {
+				return nil, errors.New("лишняя «>»")
+			}
+			optional = -1
+			rest = rest[1:]
+		case '{':
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, errors.New("незакрытая «{»")
+			}
+			token, err := parseTemplateField(rest[1:end])
+			if err != nil {
+				return nil, err
+			}
+			token.optional = optional
+			tokens = append(tokens, token)
+			rest = rest[end+1:]
+		case '}':
+			return nil, errors.New("лишняя «}»")
+		default:
+			end := strings.IndexAny(rest, "<>{}")
+			if end < 0 {
+				end = len(rest)
+			}
+			tokens = append(tokens, templateToken{text: rest[:end], optional: optional})
+			rest = rest[end:]
+		}
+	}
+	if optional >= 0 {
+		return nil, errors.New("незакрытая «<»")
+	}
+	return tokens, nil
+}
+
+func parseTemplateField(spec string) (templateToken, error) {
+	name, format, hasFormat := strings.Cut(spec, ":")
+	if !slices.Contains(templateFields, name) {
+		return templateToken{}, fmt.Errorf("неизвестное поле {%s}", name)
+	}
+	token := templateToken{field: name}
+	if hasFormat {
+		width, err := strconv.Atoi(format)
+		if err != nil || !strings.HasPrefix(format, "0") || width <= 0 {
+			return templateToken{}, fmt.Errorf("неверный формат {%s}: ожидается {%s:0N}", spec, name)
+		}
+		token.width = width
+	}
+	return token, nil
+}
+
+// renderTemplate fills a template; fields missing from values are empty.
+func renderTemplate(tmpl string, values map[string]string) (string, error) {
+	tokens, err := parseTemplate(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i := 0; i < len(tokens); {
+		if tokens[i].optional < 0 {
+			b.WriteString(tokens[i].value(values))
+			i++
+			continue
+		}
+		var part strings.Builder
+		complete := true
+		group := tokens[i].optional
+		for ; i < len(tokens) && tokens[i].optional == group; i++ {
+			v := tokens[i].value(values)
+			if tokens[i].field != "" && v == "" {
+				complete = false
+			}
+			part.WriteString(v)
+		}
+		if complete {
+			b.WriteString(part.String())
+		}
+	}
+	return b.String(), nil
+}
+
+func (t templateToken) value(values map[string]string) string {
+	if t.field == "" {
+		return t.text
+	}
+	v := values[t.field]
+	if t.width > 0 && v != "" {
+		v = padNumber(v, t.width)
+	}
+	return v
+}
+
+// validate checks both templates; empty ones are allowed when the profile
+// inherits the global naming.
+func (n NamingSettings) validate(allowEmpty bool) error {
+	if !allowEmpty && (n.Dir == "" || n.File == "") {
+		return errors.New("шаблоны dir и file должны быть заданы")
+	}
+	if _, err := parseTemplate(n.Dir); err != nil {
+		return fmt.Errorf("dir: %w", err)
+	}
+	if _, err := parseTemplate(n.File); err != nil {
+		return fmt.Errorf("file: %w", err)
+	}
+	if strings.ContainsAny(n.File, `/\`) {
+		return errors.New("file: имя файла не может содержать «/»")
+	}
+	switch n.Charset {
+	case CharsetUnicode, CharsetASCII:
+	case "":
+		if !allowEmpty {
+			return errors.New("charset не задан")
+		}
+	default:
+		return fmt.Errorf("charset: неизвестное значение %q", n.Charset)
+	}
+	return nil
+}
+
+// naming returns the profile templates with the global ones filling the gaps.
+func (p OutputProfile) naming() NamingSettings {
+	n := Config.Naming
+	if p.Naming.Dir != "" {
+		n.Dir = p.Naming.Dir
+	}
+	if p.Naming.File != "" {
+		n.File = p.Naming.File
+	}
+	if p.Naming.Charset != "" {
+		n.Charset = p.Naming.Charset
+	}
+	return n
+}
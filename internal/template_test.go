@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	values := map[string]string{"series": "Title", "volume": "3", "group": ""}
+	cases := []struct{ tmpl, want string }{
+		{"{series} v{volume:02}", "Title v03"},
+		{"{series}< v{volume:03}>< [{group}]>", "Title v003"},
+		{"{series}<_Part_{part}>", "Title"},
+		{"{profile}/{series}", "/Title"},
+	}
+	for _, tc := range cases {
+		got, err := renderTemplate(tc.tmpl, values)
+		if err != nil || got != tc.want {
+			t.Fatalf("renderTemplate(%q) = %q, %v; want %q", tc.tmpl, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, tmpl := range []string{"{unknown}", "{volume:2}", "{volume:0x}", "{series", "series}", "<{series}", "{series}>", "<<{series}>>"} {
+		if _, err := parseTemplate(tmpl); err == nil {
+			t.Fatalf("parseTemplate(%q) should fail", tmpl)
+		}
+	}
+	if err := (NamingSettings{Dir: "{series}", File: "{series}/{volume}"}).validate(false); err == nil {
+		t.Fatal("a file template with a slash should be rejected")
+	}
+}
+
+func TestLoadSettingsProfileNaming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"naming": {"file": "{series} v{volume:02}"}, "profiles": [{"name": "komga", "naming": {"dir": "komga/{series}"}}]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("LoadSettings error: %v", err)
+	}
+	if cfg.Naming.Dir != DefaultDirTemplate {
+		t.Fatalf("naming.dir = %q, want default", cfg.Naming.Dir)
+	}
+
+	original := Config
+	Config = cfg
+	t.Cleanup(func() { Config = original })
+
+	meta := &Metadata{Title: "Title — Том 1", Volume: "1", Series: Series{Title: "Title"}}
+	dir, file, err := outputPath(cfg.Profiles[0], meta, bookName{Folder: "Title", Label: "Vol_01"}, "")
+	if err != nil {
+		t.Fatalf("outputPath error: %v", err)
+	}
+	if dir != filepath.Join("output", "komga", "Title") || file != "Title_v01" {
+		t.Fatalf("outputPath = %q, %q", dir, file)
+	}
+
+	bad := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(bad, []byte(`{"profiles": [{"naming": {"file": "{nope}"}}]}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadSettings(bad); err == nil {
+		t.Fatal("an unknown template field should fail at startup")
+	}
+}
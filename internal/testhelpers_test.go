@@ -22,10 +22,12 @@ func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 
 func stubHTTPClient(t *testing.T, fn roundTripFunc) {
 	t.Helper()
-	original := http.DefaultClient.Transport
+	original, interval := http.DefaultClient.Transport, shikimoriInterval
 	http.DefaultClient.Transport = fn
+	shikimoriInterval = 0
 	t.Cleanup(func() {
 		http.DefaultClient.Transport = original
+		shikimoriInterval = interval
 	})
 }
 
@@ -122,3 +124,40 @@ func zipDirectory(t *testing.T, rootDir, zipPath string) {
 		t.Fatalf("write zip %s: %v", zipPath, err)
 	}
 }
+
+// writeImageFile encodes img as PNG or JPEG depending on the extension.
+func writeImageFile(t *testing.T, path string, img image.Image) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	defer file.Close()
+	if filepath.Ext(path) == ".png" {
+		err = png.Encode(file, img)
+	} else {
+		err = jpeg.Encode(file, img, &jpeg.Options{Quality: 95})
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", path, err)
+	}
+}
+
+// framedPage returns a white page with a black content block inside the given
+// rectangle.
+func framedPage(width, height int, content image.Rectangle) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (image.Point{X: x, Y: y}).In(content) {
+				img.SetGray(x, y, color.Gray{Y: 20})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	TitleRussian = "russian"
+	TitleEnglish = "english"
+	TitleRomaji  = "romaji"
+	TitleNative  = "native"
+	TitleFolder  = "folder" // manga folder name without release tags
+)
+
+// Titles are the series names a provider knows, by language.
+type Titles struct {
+	Russian string
+	English string
+	Romaji  string
+	Native  string
+}
+
+func (t Titles) get(kind string) string {
+	switch kind {
+	case TitleRussian:
+		return t.Russian
+	case TitleEnglish:
+		return t.English
+	case TitleRomaji:
+		return t.Romaji
+	case TitleNative:
+		return t.Native
+	}
+	return ""
+}
+
+// chooseTitle sets the series Title to the first non-empty title in
+// Config.TitlePreference, falling back to the folder name so the title is
+// never empty. The remaining titles become AltTitles.
+func (m *Series) chooseTitle(folder string) {
+	m.Title = ""
+	for _, kind := range Config.TitlePreference {
+		title := m.Titles.get(kind)
+		if kind == TitleFolder {
+			title = folder
+		}
+		if title != "" {
+			m.Title = title
+			break
+		}
+	}
+	if m.Title == "" {
+		m.Title = folder
+	}
+
+	m.AltTitles = nil
+	order := append(append([]string{}, Config.TitlePreference...), TitleRussian, TitleEnglish, TitleRomaji, TitleNative)
+	for _, kind := range order {
+		if title := m.Titles.get(kind); title != "" && title != m.Title {
+			m.AltTitles = mergeTags(m.AltTitles, title)
+		}
+	}
+}
+
+func validateTitlePreference(kinds []string) error {
+	if len(kinds) == 0 {
+		return errors.New("title_preference пуст")
+	}
+	for _, kind := range kinds {
+		switch kind {
+		case TitleRussian, TitleEnglish, TitleRomaji, TitleNative, TitleFolder:
+		default:
+			return fmt.Errorf("title_preference: неизвестный вариант %q", kind)
+		}
+	}
+	return nil
+}
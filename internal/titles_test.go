@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChooseTitle(t *testing.T) {
+	titles := Titles{English: "Attack on Titan", Romaji: "Shingeki no Kyojin", Native: "進撃の巨人"}
+
+	meta := &Series{Titles: titles}
+	meta.chooseTitle("Folder")
+	if meta.Title != "Attack on Titan" {
+		t.Fatalf("empty russian title should fall through to english, got %q", meta.Title)
+	}
+	if want := []string{"Shingeki no Kyojin", "進撃の巨人"}; !reflect.DeepEqual(meta.AltTitles, want) {
+		t.Fatalf("AltTitles = %v, want %v", meta.AltTitles, want)
+	}
+
+	original := Config
+	settings := *Config
+	settings.TitlePreference = []string{TitleNative, TitleRomaji}
+	Config = &settings
+	t.Cleanup(func() { Config = original })
+
+	meta.chooseTitle("Folder")
+	if meta.Title != "進撃の巨人" {
+		t.Fatalf("native preference ignored, got %q", meta.Title)
+	}
+	if want := []string{"Shingeki no Kyojin", "Attack on Titan"}; !reflect.DeepEqual(meta.AltTitles, want) {
+		t.Fatalf("AltTitles = %v, want %v", meta.AltTitles, want)
+	}
+
+	empty := &Series{}
+	empty.chooseTitle("Folder")
+	if empty.Title != "Folder" || empty.AltTitles != nil {
+		t.Fatalf("without titles the folder name is used, got %q %v", empty.Title, empty.AltTitles)
+	}
+}
+
+func TestTitlePreferenceConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"title_preference": ["russian", "kanji"]}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadSettings(path); err == nil || !strings.Contains(err.Error(), "kanji") {
+		t.Fatalf("unknown title kind should be rejected, got %v", err)
+	}
+}
+
+func TestComicInfoLocalizedSeries(t *testing.T) {
+	meta := &Metadata{Series: Series{Title: "Атака титанов", AltTitles: []string{"Attack on Titan", "進撃の巨人"}}}
+	data, err := buildComicInfo(nil, meta)
+	if err != nil {
+		t.Fatalf("buildComicInfo error: %v", err)
+	}
+	if want := "<LocalizedSeries>Attack on Titan</LocalizedSeries>"; !strings.Contains(string(data), want) {
+		t.Fatalf("ComicInfo.xml missing %s, got %s", want, data)
+	}
+}
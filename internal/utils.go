@@ -7,6 +7,7 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -73,10 +74,6 @@ func isImage(name string) bool {
 	return ext == ".jpg" || ext == ".jpeg" || ext == ".png"
 }
 
-func SafeName(name string) string {
-	return strings.ReplaceAll(name, " ", "_")
-}
-
 func DownloadFile(url, filepath string) error {
 	log.Printf("⬇️ Скачивание файла: %s", url)
 	resp, err := http.Get(url)
@@ -99,6 +96,42 @@ func DownloadFile(url, filepath string) error {
 	return err
 }
 
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyDir copies the regular files and folders under src to dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		return copyFile(path, target)
+	})
+}
+
 func ListImages(folder string) ([]string, error) {
 	var images []string
 	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
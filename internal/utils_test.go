@@ -92,6 +92,24 @@ func TestListImages(t *testing.T) {
 	}
 }
 
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	writePNG(t, filepath.Join(src, "001.png"), 5, 5)
+	writePNG(t, filepath.Join(src, "extra", "002.png"), 5, 5)
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyDir(src, dst); err != nil {
+		t.Fatalf("copyDir error: %v", err)
+	}
+	for _, name := range []string{"001.png", filepath.Join("extra", "002.png")} {
+		want, _ := os.ReadFile(filepath.Join(src, name))
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("%s not copied: %v", name, err)
+		}
+	}
+}
+
 func TestImageSize(t *testing.T) {
 	tmp := filepath.Join(t.TempDir(), "sample.png")
 	writePNG(t, tmp, 128, 256)
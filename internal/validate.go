@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	ValidationFail = "fail" // a broken page fails the whole volume
+	ValidationSkip = "skip" // broken pages are left out
+	ValidationWarn = "warn" // broken pages are packed, with a warning
+)
+
+const (
+	ProblemZeroByte       = "zero-byte"
+	ProblemTruncated      = "truncated"
+	ProblemWrongExtension = "wrong-extension"
+	ProblemUndecodable    = "undecodable"
+)
+
+type ValidationSettings struct {
+	Policy string `json:"policy"`
+}
+
+// ValidatePages fully decodes every page and applies the policy to the
+// broken ones. Findings are recorded in the volume report.
+func ValidatePages(pages []*Page, policy string, report *VolumeReport) ([]*Page, error) {
+	type check struct{ problem, detail string }
+	checks := make([]check, len(pages))
+	forEachPage(pages, func(i int, p *Page) error {
+		checks[i].problem, checks[i].detail = checkPage(p.Path)
+		return nil
+	})
+
+	out := make([]*Page, 0, len(pages))
+	var broken []string
+	for i, p := range pages {
+		problem, detail := checks[i].problem, checks[i].detail
+		if problem == "" {
+			out = append(out, p)
+			continue
+		}
+
+		action := "packed"
+		switch policy {
+		case ValidationFail:
+			action = "failed"
+		case ValidationSkip:
+			action = "skipped"
+		default:
+			p.Problem = problem
+			out = append(out, p)
+		}
+		log.Printf("⚠️ Страница %s: %s (%s), действие: %s", p.Name, problem, detail, action)
+		report.AddFinding(Finding{Page: p.Name, Problem: problem, Detail: detail, Action: action})
+		broken = append(broken, p.Name)
+	}
+
+	if policy == ValidationFail && len(broken) > 0 {
+		return nil, fmt.Errorf("повреждённые страницы: %s", strings.Join(broken, ", "))
+	}
+	return out, nil
+}
+
+// checkPage classifies a page file. An empty problem means the page decoded
+// completely and matches its extension.
+func checkPage(filePath string) (problem string, detail string) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return ProblemUndecodable, err.Error()
+	}
+	if len(data) == 0 {
+		return ProblemZeroByte, "пустой файл"
+	}
+
+	want := formatByExtension(filePath)
+	sniffed := sniffFormat(data)
+	if sniffed != "" && sniffed != want {
+		return ProblemWrongExtension, fmt.Sprintf("расширение %s, содержимое %s", path.Ext(filePath), sniffed)
+	}
+
+	_, _, err = image.Decode(bytes.NewReader(data))
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return ProblemTruncated, err.Error()
+	}
+	if err != nil {
+		return ProblemUndecodable, err.Error()
+	}
+	return "", ""
+}
+
+func formatByExtension(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png":
+		return "png"
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	}
+	return ""
+}
+
+func sniffFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "jpeg"
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return "gif"
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WEBP":
+		return "webp"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return "bmp"
+	}
+	return ""
+}
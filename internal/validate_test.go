@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckPage(t *testing.T) {
+	dir := t.TempDir()
+
+	good := filepath.Join(dir, "good.jpg")
+	writeJPEG(t, good, 32, 32)
+
+	empty := filepath.Join(dir, "empty.jpg")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatalf("write empty: %v", err)
+	}
+
+	data, err := os.ReadFile(good)
+	if err != nil {
+		t.Fatalf("read good: %v", err)
+	}
+	truncated := filepath.Join(dir, "truncated.jpg")
+	if err := os.WriteFile(truncated, data[:len(data)/2], 0o644); err != nil {
+		t.Fatalf("write truncated: %v", err)
+	}
+
+	misnamed := filepath.Join(dir, "misnamed.png")
+	if err := os.WriteFile(misnamed, data, 0o644); err != nil {
+		t.Fatalf("write misnamed: %v", err)
+	}
+
+	pngPath := filepath.Join(dir, "source.png")
+	writePNG(t, pngPath, 8, 8)
+	garbage, err := os.ReadFile(pngPath)
+	if err != nil {
+		t.Fatalf("read png: %v", err)
+	}
+	// Corrupt the IHDR width so the chunk checksum no longer matches.
+	garbage[18] ^= 0xff
+	corrupt := filepath.Join(dir, "corrupt.png")
+	if err := os.WriteFile(corrupt, garbage, 0o644); err != nil {
+		t.Fatalf("write corrupt: %v", err)
+	}
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{good, ""},
+		{empty, ProblemZeroByte},
+		{truncated, ProblemTruncated},
+		{misnamed, ProblemWrongExtension},
+		{corrupt, ProblemUndecodable},
+	}
+	for _, tc := range cases {
+		if got, detail := checkPage(tc.path); got != tc.want {
+			t.Fatalf("checkPage(%s) = %q (%s), want %q", filepath.Base(tc.path), got, detail, tc.want)
+		}
+	}
+}
+
+func brokenVolume(t *testing.T) []*Page {
+	t.Helper()
+	dir := t.TempDir()
+	writeJPEG(t, filepath.Join(dir, "001.jpg"), 8, 8)
+	if err := os.WriteFile(filepath.Join(dir, "002.jpg"), nil, 0o644); err != nil {
+		t.Fatalf("write empty page: %v", err)
+	}
+	pages, err := LoadPages(dir)
+	if err != nil {
+		t.Fatalf("LoadPages error: %v", err)
+	}
+	return pages
+}
+
+func TestValidatePagesPolicies(t *testing.T) {
+	report := &VolumeReport{}
+	got, err := ValidatePages(brokenVolume(t), ValidationSkip, report)
+	if err != nil {
+		t.Fatalf("skip policy error: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "001.jpg" {
+		t.Fatalf("skip policy kept %d pages", len(got))
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Problem != ProblemZeroByte || report.Findings[0].Action != "skipped" {
+		t.Fatalf("unexpected findings: %+v", report.Findings)
+	}
+
+	got, err = ValidatePages(brokenVolume(t), ValidationWarn, &VolumeReport{})
+	if err != nil {
+		t.Fatalf("warn policy error: %v", err)
+	}
+	if len(got) != 2 || got[1].Problem != ProblemZeroByte {
+		t.Fatalf("warn policy should keep and flag the page, got %+v", got)
+	}
+
+	_, err = ValidatePages(brokenVolume(t), ValidationFail, &VolumeReport{})
+	if err == nil || !strings.Contains(err.Error(), "002.jpg") {
+		t.Fatalf("fail policy error = %v, want mention of 002.jpg", err)
+	}
+}
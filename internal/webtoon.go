@@ -0,0 +1,248 @@
+package internal
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	LongStripOff  = "off"
+	LongStripOn   = "on"
+	LongStripAuto = "auto"
+)
+
+// gutterTolerance is the luminance deviation allowed inside a whitespace
+// gutter between two panels of a strip.
+const gutterTolerance = 12
+
+// gutterSearch is the share of the target page height searched upwards for a
+// gutter before falling back to a hard cut.
+const gutterSearch = 0.3
+
+type LongStripSettings struct {
+	// Mode is off, on (every chapter) or auto (chapters that look like a
+	// webtoon by aspect ratio).
+	Mode         string `json:"mode"`
+	DeviceWidth  int    `json:"device_width"`
+	DeviceHeight int    `json:"device_height"`
+	// AutoAspect is the median height/width ratio from which a chapter is
+	// treated as a webtoon in auto mode.
+	AutoAspect float64 `json:"auto_aspect"`
+}
+
+// applyLongStrip stitches every chapter into one vertical strip and re-slices
+// it into pages with the device aspect ratio, cutting at gutters where
+// possible. Pages of a chapter share the directory part of their name.
+func applyLongStrip(pages []*Page, cfg LongStripSettings, dir string) ([]*Page, error) {
+	if cfg.Mode == LongStripOff {
+		return pages, nil
+	}
+
+	var out []*Page
+	for _, chapter := range groupByChapter(pages) {
+		if cfg.Mode == LongStripAuto && !isWebtoon(chapter, cfg.AutoAspect) {
+			out = append(out, chapter...)
+			continue
+		}
+		slices, err := restitch(chapter, cfg, dir)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, slices...)
+	}
+	return out, nil
+}
+
+func groupByChapter(pages []*Page) [][]*Page {
+	var groups [][]*Page
+	for i, p := range pages {
+		if i == 0 || path.Dir(p.Name) != path.Dir(pages[i-1].Name) {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], p)
+	}
+	return groups
+}
+
+// isWebtoon looks at the median aspect ratio so a few tall credit pages in a
+// regular volume do not switch it to long-strip mode.
+func isWebtoon(pages []*Page, minAspect float64) bool {
+	var ratios []float64
+	for _, p := range pages {
+		if p.Width > 0 {
+			ratios = append(ratios, float64(p.Height)/float64(p.Width))
+		}
+	}
+	if len(ratios) == 0 {
+		return false
+	}
+	sort.Float64s(ratios)
+	return ratios[len(ratios)/2] >= minAspect
+}
+
+type stripSource struct {
+	page   *Page
+	offset int
+}
+
+func restitch(chapter []*Page, cfg LongStripSettings, dir string) ([]*Page, error) {
+	var sources []stripSource
+	var broken []*Page
+	width, total := 0, 0
+	for _, p := range chapter {
+		if p.Problem != "" {
+			// The chapter bookmark moves to the first slice.
+			page := *p
+			page.Bookmark = ""
+			broken = append(broken, &page)
+			continue
+		}
+		sources = append(sources, stripSource{page: p, offset: total})
+		total += p.Height
+		width = max(width, p.Width)
+	}
+	if width == 0 || total == 0 {
+		return chapter, nil
+	}
+
+	gutters, err := stripGutters(sources, total)
+	if err != nil {
+		return nil, err
+	}
+	sliceHeight := width * cfg.DeviceHeight / cfg.DeviceWidth
+	cuts := stripCuts(gutters, sliceHeight)
+
+	chapterDir := path.Dir(chapter[0].Name)
+	ext := path.Ext(chapter[0].Name)
+	var cache decodedCache
+	slices := make([]*Page, 0, len(cuts))
+	start := 0
+	for i, end := range cuts {
+		img, err := renderSlice(sources, &cache, width, start, end)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Join(chapterDir, fmt.Sprintf("%04d%s", i+1, ext))
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := encodeImage(target, img); err != nil {
+			return nil, err
+		}
+		slices = append(slices, &Page{
+			Path:           target,
+			Name:           name,
+			Width:          width,
+			Height:         end - start,
+			OriginalWidth:  width,
+			OriginalHeight: end - start,
+		})
+		start = end
+	}
+
+	if len(slices) > 0 {
+		slices[0].Bookmark = chapter[0].Bookmark
+	}
+	log.Printf("📜 Ленточный режим %s: %d изображений → %d страниц", chapterDir, len(chapter), len(slices))
+	// Broken pages cannot be stitched; they follow the chapter unchanged.
+	return append(slices, broken...), nil
+}
+
+// stripGutters marks every row of the strip that is uniform enough to cut at.
+func stripGutters(sources []stripSource, total int) ([]bool, error) {
+	gutters := make([]bool, total)
+	for _, src := range sources {
+		img, _, err := decodeImage(src.page.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.page.Name, err)
+		}
+		b := img.Bounds()
+		line := make([]uint8, b.Dx())
+		for y := 0; y < b.Dy() && y < src.page.Height; y++ {
+			for x := range line {
+				line[x] = luma(img, b.Min.X+x, b.Min.Y+y)
+			}
+			gutters[src.offset+y] = uniformLine(line, medianLuma(line), gutterTolerance)
+		}
+	}
+	return gutters, nil
+}
+
+// stripCuts returns the end row of every slice. A cut is moved up to the
+// closest gutter row within gutterSearch of the target height. A narrow strip
+// on a wide device can round the height down to 0; slices are at least one
+// row high.
+func stripCuts(gutters []bool, sliceHeight int) []int {
+	sliceHeight = max(sliceHeight, 1)
+	total := len(gutters)
+	var cuts []int
+	pos := 0
+	for pos < total {
+		target := pos + sliceHeight
+		if target >= total {
+			cuts = append(cuts, total)
+			break
+		}
+		cut := target
+		limit := target - int(float64(sliceHeight)*gutterSearch)
+		for y := target; y > limit && y > pos; y-- {
+			if gutters[y] {
+				cut = y
+				break
+			}
+		}
+		cuts = append(cuts, cut)
+		pos = cut
+	}
+	return cuts
+}
+
+// decodedCache keeps the last decoded source: slices are rendered top to
+// bottom, so each source is decoded at most once per slice boundary.
+type decodedCache struct {
+	path string
+	img  image.Image
+}
+
+func (c *decodedCache) get(p string) (image.Image, error) {
+	if c.path == p {
+		return c.img, nil
+	}
+	img, _, err := decodeImage(p)
+	if err != nil {
+		return nil, err
+	}
+	c.path, c.img = p, img
+	return img, nil
+}
+
+func renderSlice(sources []stripSource, cache *decodedCache, width, start, end int) (image.Image, error) {
+	dst := image.NewRGBA(image.Rect(0, 0, width, end-start))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for _, src := range sources {
+		top, bottom := src.offset, src.offset+src.page.Height
+		if bottom <= start || top >= end {
+			continue
+		}
+		img, err := cache.get(src.page.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.page.Name, err)
+		}
+		b := img.Bounds()
+		// Narrower images are centred on a white background.
+		left := (width - b.Dx()) / 2
+		from, to := max(top, start), min(bottom, end)
+		rect := image.Rect(left, from-start, left+b.Dx(), to-start)
+		draw.Draw(dst, rect, img, image.Pt(b.Min.X, b.Min.Y+from-top), draw.Src)
+	}
+	return dst, nil
+}
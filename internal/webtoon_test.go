@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"image"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsWebtoon(t *testing.T) {
+	tall := []*Page{{Width: 800, Height: 4000}, {Width: 800, Height: 3000}, {Width: 800, Height: 1200}}
+	regular := []*Page{{Width: 800, Height: 1200}, {Width: 800, Height: 1150}, {Width: 800, Height: 4000}}
+
+	if !isWebtoon(tall, 2.5) {
+		t.Fatal("tall strips should be detected as webtoon")
+	}
+	if isWebtoon(regular, 2.5) {
+		t.Fatal("regular pages should not be detected as webtoon")
+	}
+}
+
+func TestStripCutsPrefersGutter(t *testing.T) {
+	gutters := make([]bool, 250)
+	gutters[90] = true
+
+	got := stripCuts(gutters, 100)
+	want := []int{90, 190, 250}
+	if len(got) != len(want) {
+		t.Fatalf("stripCuts = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stripCuts = %v, want %v", got, want)
+		}
+	}
+
+	// A zero slice height (3px strip on a 1000x1 device) must still end.
+	if got := stripCuts(make([]bool, 3), 0); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("stripCuts with zero height = %v", got)
+	}
+}
+
+func TestApplyLongStrip(t *testing.T) {
+	src := t.TempDir()
+	// Two strips with content blocks separated by white gutters.
+	first := filepath.Join(src, "ch1", "01.png")
+	second := filepath.Join(src, "ch1", "02.png")
+	writeImageFile(t, first, framedPage(30, 100, image.Rect(0, 0, 30, 60)))
+	writeImageFile(t, second, framedPage(30, 100, image.Rect(0, 10, 30, 100)))
+
+	pages, err := LoadPages(src)
+	if err != nil {
+		t.Fatalf("LoadPages error: %v", err)
+	}
+
+	cfg := LongStripSettings{Mode: LongStripAuto, DeviceWidth: 3, DeviceHeight: 4, AutoAspect: 2.5}
+	got, err := applyLongStrip(pages, cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("applyLongStrip error: %v", err)
+	}
+
+	total := 0
+	for _, p := range got {
+		total += p.Height
+		if p.Width != 30 {
+			t.Fatalf("slice width = %d, want 30", p.Width)
+		}
+		if filepath.Dir(filepath.FromSlash(p.Name)) != "ch1" {
+			t.Fatalf("slice %s should stay in chapter folder", p.Name)
+		}
+	}
+	if total != 200 {
+		t.Fatalf("slices cover %d rows, want 200", total)
+	}
+	// Device aspect 3:4 on a 30px strip gives 40px slices.
+	if len(got) != 5 {
+		t.Fatalf("got %d slices, want 5", len(got))
+	}
+}
+
+func TestRestitchMovesBookmark(t *testing.T) {
+	src := t.TempDir()
+	writeImageFile(t, filepath.Join(src, "ch1", "01.png"), framedPage(30, 100, image.Rect(0, 0, 30, 60)))
+	pages, err := LoadPages(src)
+	if err != nil {
+		t.Fatalf("LoadPages error: %v", err)
+	}
+	broken := &Page{Path: filepath.Join(src, "ch1", "00.png"), Name: "ch1/00.png", Problem: ProblemTruncated, Bookmark: "Глава 1"}
+	chapter := append([]*Page{broken}, pages...)
+
+	cfg := LongStripSettings{Mode: LongStripOn, DeviceWidth: 3, DeviceHeight: 4}
+	got, err := restitch(chapter, cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("restitch error: %v", err)
+	}
+	var bookmarks []string
+	for _, p := range got {
+		if p.Bookmark != "" {
+			bookmarks = append(bookmarks, p.Name)
+		}
+	}
+	if !reflect.DeepEqual(bookmarks, []string{got[0].Name}) || got[0].Problem != "" {
+		t.Fatalf("bookmark should move to the first slice, got it on %v", bookmarks)
+	}
+	if broken.Bookmark == "" {
+		t.Fatal("source page must not be modified")
+	}
+}
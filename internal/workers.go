@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"runtime"
+	"sync"
+)
+
+type ParallelSettings struct {
+	// Workers is the number of pages processed at once; 0 means one per CPU.
+	Workers int `json:"workers"`
+	// MemoryMB caps the decoded size (4 bytes per pixel) of the pages being
+	// processed at the same time. A single page larger than the budget is
+	// still processed, alone. 0 disables the cap.
+	MemoryMB int `json:"memory_mb"`
+}
+
+// forEachPage calls fn for every page on a bounded worker pool. fn receives
+// the page index, so results stored by index keep the reading order no matter
+// which worker finishes first. The error of the lowest failing index is
+// returned.
+func forEachPage(pages []*Page, fn func(i int, p *Page) error) error {
+	cfg := Config.Parallel
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	budget := newMemoryBudget(int64(cfg.MemoryMB) << 20)
+
+	errs := make([]error, len(pages))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(pages)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				cost := pageCost(pages[i])
+				budget.acquire(cost)
+				errs[i] = fn(i, pages[i])
+				budget.release(cost)
+			}
+		}()
+	}
+	for i := range pages {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageCost estimates the memory of a decoded page.
+func pageCost(p *Page) int64 {
+	return int64(p.Width) * int64(p.Height) * 4
+}
+
+// memoryBudget is a weighted semaphore over bytes of decoded pixels.
+type memoryBudget struct {
+	limit int64
+	used  int64
+	mu    sync.Mutex
+	cond  *sync.Cond
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	b := &memoryBudget{limit: limit}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *memoryBudget) acquire(n int64) {
+	if b.limit <= 0 {
+		return
+	}
+	b.mu.Lock()
+	for b.used > 0 && b.used+n > b.limit {
+		b.cond.Wait()
+	}
+	b.used += n
+	b.mu.Unlock()
+}
+
+func (b *memoryBudget) release(n int64) {
+	if b.limit <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func withParallel(t *testing.T, cfg ParallelSettings) {
+	t.Helper()
+	original := Config
+	settings := *Config
+	settings.Parallel = cfg
+	Config = &settings
+	t.Cleanup(func() { Config = original })
+}
+
+func TestForEachPageKeepsOrder(t *testing.T) {
+	withParallel(t, ParallelSettings{Workers: 4})
+
+	pages := make([]*Page, 20)
+	for i := range pages {
+		pages[i] = &Page{Width: 10, Height: 10}
+	}
+	got := make([]int, len(pages))
+	err := forEachPage(pages, func(i int, p *Page) error {
+		// Later pages finish first.
+		time.Sleep(time.Duration(len(pages)-i) * time.Millisecond)
+		got[i] = i
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachPage error: %v", err)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("result %d stored at %d", v, i)
+		}
+	}
+}
+
+func TestForEachPageReturnsFirstError(t *testing.T) {
+	withParallel(t, ParallelSettings{Workers: 3})
+
+	pages := make([]*Page, 6)
+	for i := range pages {
+		pages[i] = &Page{}
+	}
+	errLow, errHigh := errors.New("page 1"), errors.New("page 4")
+	err := forEachPage(pages, func(i int, p *Page) error {
+		switch i {
+		case 1:
+			time.Sleep(5 * time.Millisecond)
+			return errLow
+		case 4:
+			return errHigh
+		}
+		return nil
+	})
+	if err != errLow {
+		t.Fatalf("error = %v, want %v", err, errLow)
+	}
+}
+
+func TestForEachPageMemoryBudget(t *testing.T) {
+	// Each page decodes to 1 MB; a 2 MB budget allows two at a time even
+	// with eight workers.
+	withParallel(t, ParallelSettings{Workers: 8, MemoryMB: 2})
+
+	pages := make([]*Page, 10)
+	for i := range pages {
+		pages[i] = &Page{Width: 512, Height: 512}
+	}
+	var running, peak int32
+	var mu sync.Mutex
+	err := forEachPage(pages, func(i int, p *Page) error {
+		n := atomic.AddInt32(&running, 1)
+		mu.Lock()
+		peak = max(peak, n)
+		mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEachPage error: %v", err)
+	}
+	if peak > 2 {
+		t.Fatalf("peak concurrency %d exceeds memory budget", peak)
+	}
+}
+
+func TestMemoryBudgetAllowsOversizedPage(t *testing.T) {
+	b := newMemoryBudget(10)
+	done := make(chan struct{})
+	go func() {
+		b.acquire(100)
+		b.release(100)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("oversized page should run alone instead of blocking forever")
+	}
+}